@@ -0,0 +1,51 @@
+package kmeans
+
+import (
+	"fmt"
+
+	"github.com/k----n/clusters"
+)
+
+// AbsorbSingletons removes the clusters with fewer than minSize members,
+// typically noise, and reassigns their members to the nearest of the
+// remaining centers by the configured metric. It returns the remaining
+// clusters, in their previous order, along with the new index of each of
+// the old clusters, -1 for the removed ones. The centers stay where they
+// were fitted, so they are no longer the means of the absorbed members;
+// use Recenter to move them. The clusters passed in are left unchanged. If
+// no cluster has at least minSize members, an error is returned
+func (m Kmeans) AbsorbSingletons(cc clusters.Clusters, minSize int) (clusters.Clusters, map[int]int, error) {
+	remap := make(map[int]int, len(cc))
+	var kept clusters.Clusters
+	for ci, c := range cc {
+		if len(c.Observations) < minSize {
+			remap[ci] = -1
+			continue
+		}
+		remap[ci] = len(kept)
+		kept = append(kept, clusters.Cluster{
+			Center:       append(clusters.Coordinates{}, c.Center...),
+			Observations: append(clusters.Observations{}, c.Observations...),
+		})
+	}
+	if len(kept) == 0 {
+		return nil, nil, fmt.Errorf("no cluster has at least %d members", minSize)
+	}
+
+	// assign against the centers only, so the order of absorption doesn't
+	// matter
+	centers := make(clusters.Clusters, len(kept))
+	for ci, c := range kept {
+		centers[ci].Center = c.Center
+	}
+	for ci, c := range cc {
+		if remap[ci] >= 0 {
+			continue
+		}
+		for _, o := range c.Observations {
+			nearest, _ := m.nearest(centers, o)
+			kept[nearest].Append(o)
+		}
+	}
+	return kept, remap, nil
+}
@@ -0,0 +1,38 @@
+package kmeans
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestAbsorbSingletons(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}, Observations: clusters.Observations{clusters.Coordinates{0, 1}, clusters.Coordinates{1, 0}}},
+		{Center: clusters.Coordinates{4, 0}, Observations: clusters.Observations{clusters.Coordinates{4, 0}}},
+		{Center: clusters.Coordinates{9, 0}, Observations: clusters.Observations{clusters.Coordinates{9, 1}, clusters.Coordinates{10, 0}}},
+		{Center: clusters.Coordinates{6, 0}, Observations: clusters.Observations{clusters.Coordinates{6, 0}}},
+		{Center: clusters.Coordinates{5, 5}},
+	}
+
+	km := New()
+	absorbed, remap, err := km.AbsorbSingletons(cc, 2)
+	if err != nil {
+		t.Errorf("Unexpected error absorbing: %v", err)
+		return
+	}
+	if exp := map[int]int{0: 0, 1: -1, 2: 1, 3: -1, 4: -1}; !reflect.DeepEqual(remap, exp) {
+		t.Errorf("Expected index map %v, got %v", exp, remap)
+	}
+	if len(absorbed) != 2 || len(absorbed[0].Observations) != 3 || len(absorbed[1].Observations) != 3 {
+		t.Errorf("Expected two clusters of 3 members, got %v", absorbed)
+	}
+	if !reflect.DeepEqual(absorbed[1].Center, cc[2].Center) || len(cc[0].Observations) != 2 {
+		t.Errorf("Expected the centers kept and the input unchanged, got %v and %v", absorbed, cc)
+	}
+
+	if _, _, err := km.AbsorbSingletons(cc, 3); err == nil {
+		t.Errorf("Expected error absorbing all clusters, got nil")
+	}
+}
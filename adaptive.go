@@ -0,0 +1,182 @@
+package kmeans
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/k----n/clusters"
+)
+
+// PartitionByRadius partitions the dataset into as many clusters as needed
+// for no observation to be farther than r from its cluster's center. It
+// starts with a single cluster and, until all observations are within
+// reach, adds a new center at the observation farthest from its center and
+// re-runs the k-means iterations. r is compared against the distances of
+// the observations, which for clusters.Coordinates are squared Euclidean
+// distances. The number of discovered clusters is the length of the result
+func (m Kmeans) PartitionByRadius(dataset clusters.Observations, r float64) (clusters.Clusters, error) {
+	if len(dataset) == 0 || len(dataset[0].Coordinates()) == 0 {
+		return nil, fmt.Errorf("there must be at least one dimension in the data set")
+	}
+	if r < 0 {
+		return nil, fmt.Errorf("the radius must not be negative")
+	}
+	// up to a cluster per data point
+	dataset, err := m.validate(dataset, 1, len(dataset))
+	if err != nil {
+		return nil, err
+	}
+
+	var deadline time.Time
+	if m.MaxDuration > 0 {
+		deadline = time.Now().Add(m.MaxDuration)
+	}
+	rng := m.rand()
+
+	center, err := dataset.Center()
+	if err != nil {
+		return nil, err
+	}
+	cc := clusters.Clusters{{Center: center}}
+
+	for {
+		res, err := m.iterate(dataset, cc, nil, rng, false, deadline)
+		if err != nil {
+			return nil, err
+		}
+		cc = res.clusters
+
+		farthest, dist := -1, r
+		for i, o := range dataset {
+			if d := m.distance(o, cc[res.assignment[i]].Center); d > dist {
+				farthest, dist = i, d
+			}
+		}
+		if farthest < 0 || len(cc) == len(dataset) || res.interrupted {
+			return cc, nil
+		}
+
+		cc = append(cc, clusters.Cluster{
+			Center: append(clusters.Coordinates{}, dataset[farthest].Coordinates()...),
+		})
+	}
+}
+
+// PartitionUntilCompact partitions the dataset into as many clusters as
+// needed for the average distance of every cluster's members to its center
+// to be at most maxAvgRadius, but into no more than kMax clusters. It starts
+// with a single cluster and keeps splitting the cluster with the largest
+// average distance in two, by running 2-means on its members, and re-running
+// the k-means iterations on the entire dataset. Distances are the ones of
+// the configured metric, i.e. squared Euclidean distances for
+// clusters.Coordinates. Frozen clusters never get split. The number of
+// discovered clusters is the length of the result
+func (m Kmeans) PartitionUntilCompact(dataset clusters.Observations, maxAvgRadius float64, kMax int) (clusters.Clusters, error) {
+	if len(dataset) == 0 || len(dataset[0].Coordinates()) == 0 {
+		return nil, fmt.Errorf("there must be at least one dimension in the data set")
+	}
+	if maxAvgRadius < 0 {
+		return nil, fmt.Errorf("the radius must not be negative")
+	}
+	if kMax < 1 {
+		return nil, fmt.Errorf("kMax must be greater than 0")
+	}
+	dataset, err := m.validate(dataset, 1, kMax)
+	if err != nil {
+		return nil, err
+	}
+
+	var deadline time.Time
+	if m.MaxDuration > 0 {
+		deadline = time.Now().Add(m.MaxDuration)
+	}
+	rng := m.rand()
+
+	center, err := dataset.Center()
+	if err != nil {
+		return nil, err
+	}
+	cc := clusters.Clusters{{Center: center}}
+
+	for {
+		res, err := m.iterate(dataset, cc, nil, rng, false, deadline)
+		if err != nil {
+			return nil, err
+		}
+		cc = res.clusters
+		if len(cc) >= kMax || res.interrupted {
+			return cc, nil
+		}
+
+		radii := make([]float64, len(cc))
+		sizes := make([]int, len(cc))
+		for i, ci := range res.assignment {
+			radii[ci] += m.distance(dataset[i], cc[ci].Center)
+			sizes[ci]++
+		}
+		frozen := m.frozen(len(cc))
+		worst, radius := -1, maxAvgRadius
+		for ci := range cc {
+			if sizes[ci] < 2 || (frozen != nil && frozen[ci]) {
+				continue
+			}
+			if r := radii[ci] / float64(sizes[ci]); r > radius {
+				worst, radius = ci, r
+			}
+		}
+		if worst < 0 {
+			return cc, nil
+		}
+
+		a, b, err := m.split(dataset, res.assignment, worst, cc[worst].Center, rng, deadline)
+		if err != nil {
+			return nil, err
+		}
+		cc[worst].Center = a
+		cc = append(cc, clusters.Cluster{Center: b})
+	}
+}
+
+// split runs 2-means on the data points assigned to cluster ci, seeded with
+// the member farthest from the center and the member farthest from that
+// one, and returns the two resulting centers
+func (m Kmeans) split(dataset clusters.Observations, assignment []int, ci int, center clusters.Coordinates, rng *rand.Rand, deadline time.Time) (clusters.Coordinates, clusters.Coordinates, error) {
+	var members clusters.Observations
+	var weights []float64
+	for i, a := range assignment {
+		if a == ci {
+			members = append(members, dataset[i])
+			if m.Weights != nil {
+				weights = append(weights, m.Weights[i])
+			}
+		}
+	}
+
+	farthest := func(c clusters.Coordinates) clusters.Coordinates {
+		f, dist := 0, -1.0
+		for i, o := range members {
+			if d := m.distance(o, c); d > dist {
+				f, dist = i, d
+			}
+		}
+		return append(clusters.Coordinates{}, members[f].Coordinates()...)
+	}
+	first := farthest(center)
+	second := farthest(first)
+
+	// the options referring to the entire dataset don't apply to the members
+	sub := m
+	sub.plotter = nil
+	sub.Weights = weights
+	sub.FrozenCentroids = nil
+	sub.SnapToData = false
+	sub.TrackChanges = false
+	sub.TrackTimings = false
+	sub.RecordHistory = false
+	res, err := sub.iterate(members, clusters.Clusters{{Center: first}, {Center: second}}, nil, rng, false, deadline)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res.clusters[0].Center, res.clusters[1].Center, nil
+}
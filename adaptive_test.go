@@ -0,0 +1,91 @@
+package kmeans
+
+import (
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestPartitionByRadius(t *testing.T) {
+	var d clusters.Observations
+	for _, c := range []clusters.Coordinates{{0, 0}, {0, 10}, {10, 0}} {
+		for i := 0; i < 16; i++ {
+			d = append(d, clusters.Coordinates{c[0] + float64(i%4)*0.1, c[1] + float64(i/4)*0.1})
+		}
+	}
+
+	r := 1.0
+	km := New()
+	cc, err := km.PartitionByRadius(d, r)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(cc) != 3 {
+		t.Errorf("Expected 3 clusters, got %d", len(cc))
+	}
+	for i, ci := range km.PredictAll(cc, d) {
+		if dist := d[i].Distance(cc[ci].Center); dist > r {
+			t.Errorf("Expected all observations within %f of their center, got %f", r, dist)
+		}
+	}
+
+	if cc, _ := km.PartitionByRadius(d, 1000); len(cc) != 1 {
+		t.Errorf("Expected a single cluster for a large radius, got %d", len(cc))
+	}
+	if _, err := km.PartitionByRadius(d, -1); err == nil {
+		t.Errorf("Expected error partitioning with a negative radius, got nil")
+	}
+
+	km.Weights = []float64{1, 2}
+	if _, err := km.PartitionByRadius(d, r); err == nil {
+		t.Errorf("Expected error partitioning with mismatching weights, got nil")
+	}
+	km.Weights = nil
+	km.FrozenCentroids = []int{-1}
+	if _, err := km.PartitionByRadius(d, r); err == nil {
+		t.Errorf("Expected error partitioning with an out of bounds frozen centroid, got nil")
+	}
+}
+
+func TestPartitionUntilCompact(t *testing.T) {
+	var d clusters.Observations
+	for _, c := range []clusters.Coordinates{{0, 0}, {0, 10}, {10, 0}} {
+		for i := 0; i < 16; i++ {
+			d = append(d, clusters.Coordinates{c[0] + float64(i%4)*0.1, c[1] + float64(i/4)*0.1})
+		}
+	}
+
+	r := 1.0
+	km := New()
+	cc, err := km.PartitionUntilCompact(d, r, 10)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(cc) != 3 {
+		t.Errorf("Expected 3 clusters, got %d", len(cc))
+	}
+	for _, c := range cc {
+		var sum float64
+		for _, o := range c.Observations {
+			sum += o.Distance(c.Center)
+		}
+		if avg := sum / float64(len(c.Observations)); avg > r {
+			t.Errorf("Expected an average distance of at most %f, got %f", r, avg)
+		}
+	}
+
+	if cc, _ := km.PartitionUntilCompact(d, r, 2); len(cc) != 2 {
+		t.Errorf("Expected 2 clusters for kMax 2, got %d", len(cc))
+	}
+	if cc, _ := km.PartitionUntilCompact(d, 1000, 10); len(cc) != 1 {
+		t.Errorf("Expected a single cluster for a large radius, got %d", len(cc))
+	}
+	if _, err := km.PartitionUntilCompact(d, -1, 10); err == nil {
+		t.Errorf("Expected error partitioning with a negative radius, got nil")
+	}
+	if _, err := km.PartitionUntilCompact(d, r, 0); err == nil {
+		t.Errorf("Expected error partitioning with kMax 0, got nil")
+	}
+}
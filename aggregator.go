@@ -0,0 +1,35 @@
+package kmeans
+
+import (
+	"github.com/k----n/clusters"
+)
+
+// Aggregator computes the center of a cluster from its members. It only
+// gets called for clusters with at least one member, and must return
+// coordinates with the dimensions of the members
+type Aggregator interface {
+	Aggregate(members clusters.Observations) clusters.Coordinates
+}
+
+// Mean is the Aggregator placing each center at the mean of its members,
+// which is what Partition does by default
+type Mean struct{}
+
+// Aggregate returns the mean of the members
+func (Mean) Aggregate(members clusters.Observations) clusters.Coordinates {
+	center, _ := members.Center()
+	return center
+}
+
+// aggregate moves the center of each cluster to the aggregate of its
+// members, in parallel over the clusters. Empty and frozen clusters keep
+// their center
+func (m Kmeans) aggregate(cc clusters.Clusters) {
+	frozen := m.frozen(len(cc))
+	m.forEach(len(cc), func(ci int) {
+		if len(cc[ci].Observations) == 0 || (frozen != nil && frozen[ci]) {
+			return
+		}
+		cc[ci].Center = m.Aggregator.Aggregate(cc[ci].Observations)
+	})
+}
@@ -0,0 +1,78 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+// median is an aggregator placing centers at the per-dimension median
+type median struct{}
+
+func (median) Aggregate(members clusters.Observations) clusters.Coordinates {
+	center := make(clusters.Coordinates, len(members[0].Coordinates()))
+	for j := range center {
+		var v []float64
+		for _, o := range members {
+			v = append(v, o.Coordinates()[j])
+		}
+		sort.Float64s(v)
+		center[j] = v[len(v)/2]
+	}
+	return center
+}
+
+func TestAggregator(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	// the built-in mean aggregator matches the default centers
+	partition := func(a Aggregator) clusters.Clusters {
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Aggregator = a
+		cc, err := km.Partition(d, 4)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc
+	}
+	c1, c2 := partition(nil), partition(Mean{})
+	for ci := range c1 {
+		for j := range c1[ci].Center {
+			if diff := c1[ci].Center[j] - c2[ci].Center[j]; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("Expected the mean aggregator to match the default centers, got %v and %v", c1[ci].Center, c2[ci].Center)
+			}
+		}
+	}
+
+	// the medians of the members are actual coordinates of the data points
+	seen := make(map[float64]bool)
+	for _, o := range d {
+		seen[o.Coordinates()[0]] = true
+	}
+	for _, c := range partition(median{}) {
+		if len(c.Observations) > 0 && !seen[c.Center[0]] {
+			t.Errorf("Expected the median of the members as center, got %v", c.Center)
+		}
+		if len(c.Observations) > 0 && !reflect.DeepEqual(c.Center, median{}.Aggregate(c.Observations)) {
+			t.Errorf("Expected the center to match the median of its final members, got %v", c.Center)
+		}
+	}
+
+	km := New()
+	km.Aggregator = median{}
+	km.Center = CenterPrototype
+	if _, err := km.Partition(d, 4); err == nil {
+		t.Errorf("Expected error combining an aggregator with a center method, got nil")
+	}
+}
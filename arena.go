@@ -0,0 +1,50 @@
+package kmeans
+
+import (
+	"github.com/k----n/clusters"
+)
+
+// Pack copies the coordinates of all data points of the dataset into a
+// single backing array, an arena, and returns the data points as views
+// into it, in the same order. A dataset of separately allocated
+// coordinates holds a few heap objects per data point, which the garbage
+// collector has to track and mark; the packed one holds three in total,
+// however many data points there are, which shortens the garbage
+// collections while clustering millions of small data points (the
+// collector still follows a pointer per data point out of the dataset
+// itself, so the gain is in the order of tens of percent). The packed
+// data points have the same coordinates, so they cluster exactly like the
+// original ones, but they carry no payload (see Record), and they share the
+// arena: it stays alive as long as any of them does
+func Pack(dataset clusters.Observations) clusters.Observations {
+	total := 0
+	for _, o := range dataset {
+		total += len(o.Coordinates())
+	}
+
+	data := make([]float64, 0, total)
+	rows := make([]clusters.Coordinates, len(dataset))
+	packed := make(clusters.Observations, len(dataset))
+	for i, o := range dataset {
+		start := len(data)
+		data = append(data, o.Coordinates()...)
+		// capped, so appending to a row can't overwrite the next one
+		rows[i] = data[start:len(data):len(data)]
+		packed[i] = &rows[i]
+	}
+	return packed
+}
+
+// packRows returns the rows of the row-major n×d matrix data as data
+// points referencing it, without copying
+func packRows(data []float64, n, d int) clusters.Observations {
+	rows := make([]clusters.Coordinates, n)
+	dataset := make(clusters.Observations, n)
+	for i := range rows {
+		// pointers into rows implement clusters.Observation without
+		// boxing every row separately
+		rows[i] = data[i*d : (i+1)*d : (i+1)*d]
+		dataset[i] = &rows[i]
+	}
+	return dataset
+}
@@ -0,0 +1,74 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestPack(t *testing.T) {
+	d, _ := MakeBlobs(2048, 4, 3, 1, rand.New(rand.NewSource(randomSeed)))
+	d = append(d, clusters.Coordinates{1, 2})
+	packed := Pack(d)
+	if len(packed) != len(d) {
+		t.Errorf("Expected %d packed data points, got %d", len(d), len(packed))
+		return
+	}
+	for i := range d {
+		if !reflect.DeepEqual(packed[i].Coordinates(), d[i].Coordinates()) {
+			t.Errorf("Expected coordinates %v, got %v", d[i].Coordinates(), packed[i].Coordinates())
+			return
+		}
+	}
+	// the rows are capped
+	row := append(packed[0].Coordinates(), 42)
+	if packed[1].Coordinates()[0] == 42 || len(row) != 4 {
+		t.Errorf("Expected appending to a row to leave the next one alone")
+	}
+
+	d = d[:2048]
+	partition := func(dataset clusters.Observations) clusters.Clusters {
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Init = InitKMeansPlusPlus
+		cc, err := km.Partition(dataset, 4)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc
+	}
+	exp, cc := partition(d), partition(Pack(d))
+	for ci := range exp {
+		if !reflect.DeepEqual(cc[ci].Center, exp[ci].Center) || len(cc[ci].Observations) != len(exp[ci].Observations) {
+			t.Errorf("Expected the clusters of the original data points, got %v and %v", cc[ci].Center, exp[ci].Center)
+		}
+	}
+}
+
+// benchmarkGC measures the duration of a garbage collection with a large
+// dataset alive, and reports the number of heap objects it takes
+func benchmarkGC(pack bool, b *testing.B) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	d, _ := MakeBlobs(1<<20, 16, 3, 1, rand.New(rand.NewSource(randomSeed)))
+	if pack {
+		d = Pack(d)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		runtime.GC()
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(after.HeapObjects)-float64(before.HeapObjects), "objects")
+	runtime.KeepAlive(d)
+}
+
+func BenchmarkGCUnpacked(b *testing.B) { benchmarkGC(false, b) }
+func BenchmarkGCPacked(b *testing.B)   { benchmarkGC(true, b) }
@@ -0,0 +1,60 @@
+package kmeans
+
+import (
+	"math/rand"
+
+	"github.com/k----n/clusters"
+)
+
+// stepBalanced is step for a BalancePenalty: the data points get assigned
+// one after another, in the given order if ShuffleEachIteration is set,
+// otherwise by index, each to the cluster with the lowest sum of the
+// distance to its center and the penalty for each data point assigned to it
+// so far in the iteration
+func (m Kmeans) stepBalanced(cc clusters.Clusters, dataset clusters.Observations, points []int, rng *rand.Rand, frozen []bool, iteration int, lastChanged []int) (uint64, []int) {
+	// keep the storage of the member lists for the next assignment
+	for ci := range cc {
+		cc[ci].Observations = cc[ci].Observations[:0]
+	}
+	var order []int
+	if m.ShuffleEachIteration {
+		order = rng.Perm(len(dataset))
+	}
+
+	var changes uint64
+	for n := range dataset {
+		p := n
+		if order != nil {
+			p = order[n]
+		}
+		ci, cost := -1, 0.0
+		for i, c := range cc {
+			if d := m.distance(dataset[p], c.Center) + m.BalancePenalty*float64(len(c.Observations)); ci < 0 || d < cost {
+				ci, cost = i, d
+			}
+		}
+		cc[ci].Append(dataset[p])
+		if points[p] != ci {
+			points[p] = ci
+			changes++
+			if lastChanged != nil {
+				lastChanged[p] = iteration
+			}
+		}
+	}
+	m.clock.lap(phaseAssignment)
+
+	refilled := m.refillEmpty(cc, dataset, points, rng, frozen)
+	if lastChanged != nil {
+		for _, p := range refilled {
+			lastChanged[p] = iteration
+		}
+	}
+	m.clock.lap(phaseReseed)
+
+	if changes > 0 || len(refilled) > 0 {
+		m.recenter(cc, dataset, points)
+	}
+	m.clock.lap(phaseRecenter)
+	return changes, refilled
+}
@@ -0,0 +1,52 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestBalancePenalty(t *testing.T) {
+	// a large blob and a small one
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	for i := 0; i < 100; i++ {
+		x := 0.0
+		if i >= 90 {
+			x = 10
+		}
+		d = append(d, clusters.Coordinates{x + rng.Float64(), rng.Float64()})
+	}
+
+	partition := func(penalty float64) clusters.Clusters {
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Init = InitKMeansPlusPlus
+		km.BalancePenalty = penalty
+		cc, err := km.Partition(d, 2)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc
+	}
+
+	cc := partition(0)
+	if n := len(cc[0].Observations); n != 10 && n != 90 {
+		t.Errorf("Expected the blobs as clusters without a penalty, got sizes %d and %d", n, len(cc[1].Observations))
+	}
+	cc = partition(10)
+	if n := len(cc[0].Observations); n < 45 || n > 55 {
+		t.Errorf("Expected balanced clusters with a high penalty, got sizes %d and %d", n, len(cc[1].Observations))
+	}
+	if again := partition(10); !reflect.DeepEqual(again, cc) {
+		t.Errorf("Expected identical clusters for the same seed")
+	}
+
+	km := New()
+	km.BalancePenalty = -1
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with a negative balance penalty, got nil")
+	}
+}
@@ -0,0 +1,49 @@
+package kmeans
+
+import (
+	"math/rand"
+
+	"github.com/k----n/clusters"
+)
+
+// blobBox is the extent of the box the centers of MakeBlobs get drawn from,
+// in each dimension
+const blobBox = 10.0
+
+// MakeBlobs generates n data points with dims dimensions around k random
+// centers, for tests, examples and benchmarks. The centers get drawn
+// uniformly from [-10, 10) in each dimension, and each data point from a
+// Gaussian around its center, whose standard deviation is spread in each
+// dimension. The data points get assigned to the centers in turn, so the
+// blobs differ in size by at most one. It returns the data points along with
+// the index of the center of each. All random choices get drawn from rng, so
+// the data set is reproducible for a seeded source; when rng is nil, a fresh
+// source is used. If k or dims isn't positive, nil is returned
+func MakeBlobs(n, k, dims int, spread float64, rng *rand.Rand) (clusters.Observations, []int) {
+	if k <= 0 || dims <= 0 || n < 0 {
+		return nil, nil
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(rand.Int63())) //nolint:gosec // math/rand is good enough for this
+	}
+
+	centers := make([]clusters.Coordinates, k)
+	for ci := range centers {
+		centers[ci] = make(clusters.Coordinates, dims)
+		for j := range centers[ci] {
+			centers[ci][j] = blobBox * (2*rng.Float64() - 1)
+		}
+	}
+
+	dataset := make(clusters.Observations, n)
+	labels := make([]int, n)
+	for i := range dataset {
+		ci := i % k
+		o := make(clusters.Coordinates, dims)
+		for j := range o {
+			o[j] = centers[ci][j] + spread*rng.NormFloat64()
+		}
+		dataset[i], labels[i] = o, ci
+	}
+	return dataset, labels
+}
@@ -0,0 +1,44 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestMakeBlobs(t *testing.T) {
+	d, labels := MakeBlobs(1000, 4, 3, 0.1, rand.New(rand.NewSource(randomSeed)))
+	if len(d) != 1000 || len(labels) != 1000 || len(d[0].Coordinates()) != 3 {
+		t.Errorf("Expected 1000 labeled data points of 3 dimensions, got %d and %d", len(d), len(labels))
+		return
+	}
+	sizes := make([]int, 4)
+	for _, l := range labels {
+		sizes[l]++
+	}
+	if !reflect.DeepEqual(sizes, []int{250, 250, 250, 250}) {
+		t.Errorf("Expected blobs of 250 data points, got %v", sizes)
+	}
+
+	again, _ := MakeBlobs(1000, 4, 3, 0.1, rand.New(rand.NewSource(randomSeed)))
+	if !reflect.DeepEqual(d, again) {
+		t.Errorf("Expected identical data sets for the same seed")
+	}
+
+	// well separated blobs get recovered
+	km := New()
+	km.Init = InitKMeansPlusPlus
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	cc, err := km.Partition(d, 4)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if ari := AdjustedRandIndex(km.PredictAll(cc, d), labels); ari < 0.99 {
+		t.Errorf("Expected the blobs to be recovered, got an adjusted Rand index of %f", ari)
+	}
+
+	if d, labels := MakeBlobs(10, 0, 2, 1, nil); d != nil || labels != nil {
+		t.Errorf("Expected nil for 0 blobs, got %v and %v", d, labels)
+	}
+}
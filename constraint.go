@@ -0,0 +1,38 @@
+package kmeans
+
+import (
+	"github.com/k----n/clusters"
+)
+
+// BoundingBox returns a CentroidConstraint clamping each coordinate of a
+// center between the coordinates of min and max, which must have the
+// dimensions of the data set. Use math.Inf for unbounded dimensions, e.g. a
+// min of zeros and a max of math.Inf(1) keeps all centers non-negative
+func BoundingBox(min, max clusters.Coordinates) func(c clusters.Coordinates) {
+	return func(c clusters.Coordinates) {
+		for j := range c {
+			if c[j] < min[j] {
+				c[j] = min[j]
+			}
+			if c[j] > max[j] {
+				c[j] = max[j]
+			}
+		}
+	}
+}
+
+// constrain applies the CentroidConstraint to copies of the centers of all
+// clusters which aren't frozen, since a center may share its coordinates
+// with a data point, e.g. when computed by an Aggregator
+func (m Kmeans) constrain(cc clusters.Clusters, frozen []bool) {
+	if m.CentroidConstraint == nil {
+		return
+	}
+	for ci := range cc {
+		if frozen == nil || !frozen[ci] {
+			c := append(clusters.Coordinates{}, cc[ci].Center...)
+			m.CentroidConstraint(c)
+			cc[ci].Center = c
+		}
+	}
+}
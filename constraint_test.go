@@ -0,0 +1,62 @@
+package kmeans
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestCentroidConstraint(t *testing.T) {
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		// noisy measurements of non-negative quantities
+		d = append(d, clusters.Coordinates{rng.NormFloat64() * 0.1, 5 + rng.NormFloat64()})
+	}
+	d = append(d, clusters.Coordinates{-10, 0})
+
+	for _, fused := range []bool{false, true} {
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Init = InitKMeansPlusPlus
+		km.FusedRecenter = fused
+		km.CentroidConstraint = BoundingBox(clusters.Coordinates{0, 0}, clusters.Coordinates{math.Inf(1), 5})
+		cc, err := km.Partition(d, 3)
+		if err != nil {
+			t.Errorf("Unexpected error partitioning: %v", err)
+			return
+		}
+		for _, c := range cc {
+			if c.Center[0] < 0 || c.Center[1] < 0 || c.Center[1] > 5 {
+				t.Errorf("Expected centers within the bounding box, got %v", c.Center)
+			}
+		}
+	}
+
+	// the data points themselves never get constrained
+	var orig []clusters.Coordinates
+	for _, o := range d {
+		orig = append(orig, append(clusters.Coordinates{}, o.Coordinates()...))
+	}
+	km := New()
+	km.Aggregator = first{}
+	km.CentroidConstraint = BoundingBox(clusters.Coordinates{0, 0}, clusters.Coordinates{0, 0})
+	if _, err := km.Partition(d, 2); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+	}
+	for i, o := range d {
+		if !reflect.DeepEqual(o.Coordinates(), orig[i]) {
+			t.Errorf("Expected the data to remain unchanged, got %v instead of %v", o.Coordinates(), orig[i])
+		}
+	}
+}
+
+// first is an aggregator picking the first member as the center
+type first struct{}
+
+func (first) Aggregate(members clusters.Observations) clusters.Coordinates {
+	return members[0].Coordinates()
+}
@@ -0,0 +1,82 @@
+package kmeans
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/k----n/clusters"
+)
+
+// WriteAssignmentsCSV writes one row per data point to w, holding its
+// coordinates followed by the index of its cluster, as given by assignment
+// (see PartitionInto and PredictAll). The header row names the dimensions
+// x0, x1, ... and the last column cluster
+func (m Kmeans) WriteAssignmentsCSV(w io.Writer, dataset clusters.Observations, assignment []int) error {
+	if len(assignment) != len(dataset) {
+		return fmt.Errorf("the size of the assignment must equal the size of the data set")
+	}
+
+	var dims int
+	if len(dataset) > 0 {
+		dims = len(dataset[0].Coordinates())
+	}
+	records := [][]string{append(csvDimensions(dims), "cluster")}
+	for i, o := range dataset {
+		if len(o.Coordinates()) != dims {
+			return fmt.Errorf("data point %d must have %d dimensions", i, dims)
+		}
+		records = append(records, append(csvCoordinates(o.Coordinates()), strconv.Itoa(assignment[i])))
+	}
+	return m.writeCSV(w, records)
+}
+
+// WriteCentroidsCSV writes one row per cluster to w, holding its index
+// followed by the coordinates of its center. The header row names the first
+// column cluster and the dimensions x0, x1, ...
+func (m Kmeans) WriteCentroidsCSV(w io.Writer, cc clusters.Clusters) error {
+	var dims int
+	if len(cc) > 0 {
+		dims = len(cc[0].Center)
+	}
+	records := [][]string{append([]string{"cluster"}, csvDimensions(dims)...)}
+	for ci, c := range cc {
+		if len(c.Center) != dims {
+			return fmt.Errorf("center %d must have %d dimensions", ci, dims)
+		}
+		records = append(records, append([]string{strconv.Itoa(ci)}, csvCoordinates(c.Center)...))
+	}
+	return m.writeCSV(w, records)
+}
+
+// writeCSV writes the records to w, delimited by the CSVDelimiter
+func (m Kmeans) writeCSV(w io.Writer, records [][]string) error {
+	cw := csv.NewWriter(w)
+	if m.CSVDelimiter != 0 {
+		cw.Comma = m.CSVDelimiter
+	}
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write CSV: %s", err)
+	}
+	return nil
+}
+
+// csvDimensions returns the header names of d dimensions
+func csvDimensions(d int) []string {
+	names := make([]string, d)
+	for j := range names {
+		names[j] = "x" + strconv.Itoa(j)
+	}
+	return names
+}
+
+// csvCoordinates formats the coordinates of a point as CSV fields, in the
+// shortest representation which parses back to the same values
+func csvCoordinates(c clusters.Coordinates) []string {
+	fields := make([]string, len(c))
+	for j, v := range c {
+		fields[j] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fields
+}
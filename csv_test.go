@@ -0,0 +1,45 @@
+package kmeans
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestWriteCSV(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0.5},
+		clusters.Coordinates{10, -1},
+	}
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{10, -1}},
+		{Center: clusters.Coordinates{0, 0.5}},
+	}
+
+	var buf bytes.Buffer
+	km := New()
+	if err := km.WriteAssignmentsCSV(&buf, d, []int{1, 0}); err != nil {
+		t.Errorf("Unexpected error writing assignments: %v", err)
+	}
+	if exp := "x0,x1,cluster\n0,0.5,1\n10,-1,0\n"; buf.String() != exp {
+		t.Errorf("Expected assignments %q, got %q", exp, buf.String())
+	}
+
+	buf.Reset()
+	km.CSVDelimiter = ';'
+	if err := km.WriteCentroidsCSV(&buf, cc); err != nil {
+		t.Errorf("Unexpected error writing centroids: %v", err)
+	}
+	if exp := "cluster;x0;x1\n0;10;-1\n1;0;0.5\n"; buf.String() != exp {
+		t.Errorf("Expected centroids %q, got %q", exp, buf.String())
+	}
+
+	if err := km.WriteAssignmentsCSV(&buf, d, []int{0}); err == nil {
+		t.Errorf("Expected error writing a mismatching assignment, got nil")
+	}
+	km.CSVDelimiter = '\n'
+	if err := km.WriteCentroidsCSV(&buf, cc); err == nil {
+		t.Errorf("Expected error writing with an invalid delimiter, got nil")
+	}
+}
@@ -0,0 +1,66 @@
+package kmeans
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/k----n/clusters"
+)
+
+// dedupePrecision is the number of decimal places observations get rounded
+// to before comparing them in Dedupe
+const dedupePrecision = 1e9
+
+// Dedupe collapses identical observations into one, and returns the unique
+// observations along with the number of times each of them occurred in the
+// dataset. The counts can be used as Weights for clustering the unique
+// observations, which yields the same clusters as the full dataset.
+// Observations are compared after rounding their coordinates to 9 decimal
+// places, so values which only differ beyond that are considered identical.
+// The first occurrence of each observation represents it in the result
+func Dedupe(dataset clusters.Observations) (unique clusters.Observations, weights []float64) {
+	index := make(map[string]int)
+	var buf []byte
+
+	for _, o := range dataset {
+		buf = dedupeKey(buf[:0], o)
+		if i, ok := index[string(buf)]; ok {
+			weights[i]++
+			continue
+		}
+		index[string(buf)] = len(unique)
+		unique = append(unique, o)
+		weights = append(weights, 1)
+	}
+
+	return unique, weights
+}
+
+// distinctAtLeast returns whether the dataset contains at least n distinct
+// observations, compared like Dedupe does
+func distinctAtLeast(dataset clusters.Observations, n int) bool {
+	seen := make(map[string]struct{})
+	var buf []byte
+
+	for _, o := range dataset {
+		if len(seen) >= n {
+			break
+		}
+		buf = dedupeKey(buf[:0], o)
+		seen[string(buf)] = struct{}{}
+	}
+	return len(seen) >= n
+}
+
+// dedupeKey appends the rounded coordinates of the observation to buf
+func dedupeKey(buf []byte, o clusters.Observation) []byte {
+	for _, v := range o.Coordinates() {
+		r := math.Round(v*dedupePrecision) / dedupePrecision
+		if r == 0 {
+			// treat -0 and 0 alike
+			r = 0
+		}
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(r))
+	}
+	return buf
+}
@@ -0,0 +1,32 @@
+package kmeans
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestDedupe(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{1, 2},
+		clusters.Coordinates{3, 4},
+		clusters.Coordinates{1, 2},
+		clusters.Coordinates{1, 2 + 1e-12},
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{-0.0, 0},
+	}
+
+	unique, weights := Dedupe(d)
+	exp := clusters.Observations{
+		clusters.Coordinates{1, 2},
+		clusters.Coordinates{3, 4},
+		clusters.Coordinates{0, 0},
+	}
+	if !reflect.DeepEqual(unique, exp) {
+		t.Errorf("Expected unique observations %v, got %v", exp, unique)
+	}
+	if !reflect.DeepEqual(weights, []float64{3, 1, 2}) {
+		t.Errorf("Expected weights [3 1 2], got %v", weights)
+	}
+}
@@ -0,0 +1,145 @@
+package kmeans
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/k----n/clusters"
+)
+
+// DistanceFunc is a metric between two points. The k-means objective
+// minimizes the sum of the distances of the data points to their cluster
+// center, so for the means to be the optimal centers, the metric must be
+// the squared Euclidean distance (which is what clusters.Coordinates use)
+type DistanceFunc func(a, b clusters.Coordinates) float64
+
+// FeatureType declares how Gower compares the values of a dimension
+type FeatureType int
+
+const (
+	// FeatureNumeric values are compared by their absolute difference,
+	// normalized by the range of the dimension
+	FeatureNumeric FeatureType = iota
+	// FeatureCategorical values are category codes, which either match
+	// (distance 0) or don't (distance 1)
+	FeatureCategorical
+)
+
+// Gower returns the Gower distance for mixed numeric and categorical data:
+// the average across all dimensions of the normalized absolute differences
+// of numeric features and the mismatches of categorical features. Both
+// featureTypes and ranges hold one entry per dimension, ranges[i] being the
+// extent (maximum minus minimum) of numeric dimension i in the data set;
+// the ranges of categorical dimensions are ignored, and numeric dimensions
+// without any extent never contribute. The means of category codes are no
+// meaningful centers, so mixed data needs centers which take the mode of
+// categorical features (k-prototypes)
+// See: https://doi.org/10.2307/2528823
+func Gower(featureTypes []FeatureType, ranges []float64) DistanceFunc {
+	return func(a, b clusters.Coordinates) float64 {
+		var d float64
+		for j, t := range featureTypes {
+			switch {
+			case t == FeatureCategorical:
+				if a[j] != b[j] {
+					d++
+				}
+			case ranges[j] > 0:
+				d += math.Abs(a[j]-b[j]) / ranges[j]
+			}
+		}
+		return d / float64(len(featureTypes))
+	}
+}
+
+// distance returns the distance between the observation and the
+// coordinates under the configured metric. By default this is the
+// observation's own Distance, the cosine distance in Spherical mode, or the
+// squared Euclidean distance weighing each dimension by the FeatureWeights
+// if set. The evaluation gets counted if CountDistances is set
+func (m Kmeans) distance(o clusters.Observation, c clusters.Coordinates) float64 {
+	if m.distances != nil {
+		m.distances.Add(1)
+	}
+	if m.Distance != nil {
+		return m.Distance(o.Coordinates(), c)
+	}
+	if m.Spherical {
+		return cosineDistance(o.Coordinates(), c)
+	}
+	if m.FeatureWeights == nil {
+		return o.Distance(c)
+	}
+
+	var d float64
+	for j, v := range o.Coordinates() {
+		d += m.FeatureWeights[j] * (v - c[j]) * (v - c[j])
+	}
+	return d
+}
+
+// cosineDistance returns 1 minus the cosine similarity of a and b. A zero
+// vector has a distance of 1 to any other vector
+func cosineDistance(a, b clusters.Coordinates) float64 {
+	var dot, na, nb float64
+	for j := range a {
+		dot += a[j] * b[j]
+		na += a[j] * a[j]
+		nb += b[j] * b[j]
+	}
+	if na == 0 || nb == 0 {
+		return 1
+	}
+	return 1 - dot/math.Sqrt(na*nb)
+}
+
+// NormalizeCentroids scales the center of each cluster to unit length (L2
+// norm), in place. Centers at the origin are left unchanged
+func NormalizeCentroids(cc clusters.Clusters) {
+	normalizeCenters(cc, nil)
+}
+
+// normalizeCenters scales the center of each cluster which isn't frozen to
+// unit length
+func normalizeCenters(cc clusters.Clusters, frozen []bool) {
+	for ci := range cc {
+		if frozen != nil && frozen[ci] {
+			continue
+		}
+
+		var norm float64
+		for _, v := range cc[ci].Center {
+			norm += v * v
+		}
+		if norm == 0 {
+			continue
+		}
+		norm = math.Sqrt(norm)
+		for j := range cc[ci].Center {
+			cc[ci].Center[j] /= norm
+		}
+	}
+}
+
+// checkMetric validates the options of the configured metric against the
+// dimensions of the dataset
+func (m Kmeans) checkMetric(dataset clusters.Observations) error {
+	if m.Spherical && (m.Distance != nil || m.FeatureWeights != nil) {
+		return fmt.Errorf("spherical mode can't be combined with a custom distance or feature weights")
+	}
+	if m.FeatureWeights == nil {
+		return nil
+	}
+	if m.Distance != nil {
+		return fmt.Errorf("feature weights can't be combined with a custom distance")
+	}
+	if len(dataset) > 0 && len(m.FeatureWeights) != len(dataset[0].Coordinates()) {
+		return fmt.Errorf("the number of feature weights must equal the number of dimensions")
+	}
+	for j, w := range m.FeatureWeights {
+		if w < 0 || math.IsNaN(w) {
+			return fmt.Errorf("feature weight %f of dimension %d must not be negative", w, j)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package kmeans
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestGower(t *testing.T) {
+	gower := Gower([]FeatureType{FeatureNumeric, FeatureCategorical, FeatureNumeric}, []float64{10, 0, 0})
+
+	a := clusters.Coordinates{2, 1, 5}
+	if d := gower(a, a); d != 0 {
+		t.Errorf("Expected distance 0 between identical points, got %f", d)
+	}
+	// (0.5 + 1 + 0) / 3
+	if d := gower(a, clusters.Coordinates{7, 3, 8}); math.Abs(d-0.5) > 1e-9 {
+		t.Errorf("Expected distance 0.5, got %f", d)
+	}
+
+	// categories 0 and 1 with spread out numeric values
+	var d clusters.Observations
+	for i := 0; i < 32; i++ {
+		d = append(d, clusters.Coordinates{float64(i % 4), float64(i % 2)})
+	}
+	km := New()
+	km.Init = InitForgy
+	km.Distance = Gower([]FeatureType{FeatureNumeric, FeatureCategorical}, []float64{3, 0})
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(cc) != 2 {
+		t.Errorf("Expected 2 clusters, got %d", len(cc))
+	}
+
+	// the matching category outweighs the numeric difference, unlike under
+	// the squared Euclidean distance
+	km.Distance = Gower([]FeatureType{FeatureNumeric, FeatureCategorical}, []float64{100, 0})
+	cc = clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}},
+		{Center: clusters.Coordinates{1, 1}},
+	}
+	if ci := km.Predict(cc, clusters.Coordinates{-2, 1}); ci != 1 {
+		t.Errorf("Expected the custom distance to predict cluster 1, got %d", ci)
+	}
+
+	km.FeatureWeights = []float64{1, 1}
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error combining feature weights with a custom distance, got nil")
+	}
+}
+
+func TestSpherical(t *testing.T) {
+	// directions around the x and the y axis, at varying lengths
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 128; i++ {
+		l := 1 + rand.Float64()*10
+		a := rand.Float64() * 0.2
+		if i%2 == 1 {
+			a += math.Pi / 2
+		}
+		d = append(d, clusters.Coordinates{l * math.Cos(a), l * math.Sin(a)})
+	}
+
+	km := New()
+	km.Init = InitKMeansPlusPlus
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Spherical = true
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	for _, c := range cc {
+		if n := math.Hypot(c.Center[0], c.Center[1]); math.Abs(n-1) > 1e-9 {
+			t.Errorf("Expected a unit length center, got %v with norm %f", c.Center, n)
+		}
+		if len(c.Observations) != 64 {
+			t.Errorf("Expected clusters of 64 directions, got %d", len(c.Observations))
+		}
+	}
+
+	km.FeatureWeights = []float64{1, 1}
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error combining spherical mode with feature weights, got nil")
+	}
+
+	cc = clusters.Clusters{{Center: clusters.Coordinates{3, 4}}, {Center: clusters.Coordinates{0, 0}}}
+	NormalizeCentroids(cc)
+	if !reflect.DeepEqual(cc[0].Center, clusters.Coordinates{0.6, 0.8}) || !reflect.DeepEqual(cc[1].Center, clusters.Coordinates{0, 0}) {
+		t.Errorf("Expected normalized centers [0.6 0.8] and [0 0], got %v and %v", cc[0].Center, cc[1].Center)
+	}
+}
@@ -0,0 +1,33 @@
+package kmeans
+
+import (
+	"fmt"
+)
+
+// PartitionFlat executes the k-means algorithm on a row-major n×d matrix of
+// data points, stored in a single contiguous slice. The rows get clustered
+// in place: they are referenced, not copied, so there is no per-row
+// allocation. It returns the k×d matrix of cluster centers in the same
+// layout, and the cluster index of each row
+func (m Kmeans) PartitionFlat(data []float64, n, d, k int) (centroids []float64, assignment []int, err error) {
+	if n <= 0 || d <= 0 {
+		return nil, nil, fmt.Errorf("there must be at least one dimension in the data set")
+	}
+	if len(data) != n*d {
+		return nil, nil, fmt.Errorf("the size of the data must equal n*d")
+	}
+
+	res, err := m.partition(packRows(data, n, d), k)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.writeCentroids(res.clusters); err != nil {
+		return nil, nil, err
+	}
+
+	centroids = make([]float64, 0, k*d)
+	for _, c := range res.clusters {
+		centroids = append(centroids, c.Center...)
+	}
+	return centroids, res.assignment, nil
+}
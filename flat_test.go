@@ -0,0 +1,49 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestPartitionFlat(t *testing.T) {
+	rand.Seed(randomSeed)
+	var data []float64
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		x, y := rand.Float64(), rand.Float64()
+		data = append(data, x, y)
+		d = append(d, clusters.Coordinates{x, y})
+	}
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	centroids, assignment, err := km.PartitionFlat(data, 256, 2, 4)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	// the same clustering as of the wrapped rows
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	res, _ := km.partition(d, 4)
+	var want []float64
+	for _, c := range res.clusters {
+		want = append(want, c.Center...)
+	}
+	if !reflect.DeepEqual(centroids, want) {
+		t.Errorf("Expected centroids %v, got %v", want, centroids)
+	}
+	if !reflect.DeepEqual(assignment, res.assignment) {
+		t.Errorf("Expected the assignment of the wrapped rows")
+	}
+
+	if _, _, err := km.PartitionFlat(data, 256, 3, 4); err == nil {
+		t.Errorf("Expected error partitioning mismatching dimensions, got nil")
+	}
+	if _, _, err := km.PartitionFlat(nil, 0, 2, 4); err == nil {
+		t.Errorf("Expected error partitioning an empty matrix, got nil")
+	}
+}
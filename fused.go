@@ -0,0 +1,161 @@
+package kmeans
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/k----n/clusters"
+)
+
+// stepFused is step for FusedRecenter: the data points get assigned in
+// chunks of fixed size, and each chunk, once the previous ones are done,
+// adds the (weighted) coordinates of its data points to the sums of their
+// clusters, so the mean centers can be derived without another pass over the
+// data. The sums run over the members of each cluster in the order of the
+// dataset, in chunks of recenterChunkSize members merged in order, exactly
+// like in recenter, so the centers are identical. Shuffled assignments and
+// refills change that order, so the centers get recomputed by recenter
+// then. The members get appended to their clusters in the order the data
+// points got processed in, unless the run is lean, which only counts them
+func (m Kmeans) stepFused(cc clusters.Clusters, dataset clusters.Observations, points []int, rng *rand.Rand, frozen []bool, iteration int, lastChanged []int) (uint64, []int) {
+	// keep the storage of the member lists for the next assignment
+	for ci := range cc {
+		cc[ci].Observations = cc[ci].Observations[:0]
+	}
+	var order []int
+	if m.ShuffleEachIteration {
+		order = rng.Perm(len(dataset))
+	}
+
+	lean := m.lean()
+	k, dims := len(cc), len(dataset[0].Coordinates())
+	chunks := (len(dataset) + recenterChunkSize - 1) / recenterChunkSize
+	// the sums of the merged chunks of members of each cluster, and of the
+	// chunk in progress
+	sum, total := make([]float64, k*dims), make([]float64, k)
+	part, partTotal := make([]float64, k*dims), make([]float64, k)
+	count := make([]int, k)
+	flush := func(ci int) {
+		for j := ci * dims; j < (ci+1)*dims; j++ {
+			sum[j] += part[j]
+			part[j] = 0
+		}
+		total[ci] += partTotal[ci]
+		partTotal[ci], count[ci] = 0, 0
+	}
+	done := make([]chan struct{}, chunks)
+	for chunk := range done {
+		done[chunk] = make(chan struct{})
+	}
+	var changes atomic.Uint64
+
+	m.forEach(chunks, func(chunk int) {
+		defer close(done[chunk])
+		start, end := chunk*recenterChunkSize, (chunk+1)*recenterChunkSize
+		if end > len(dataset) {
+			end = len(dataset)
+		}
+
+		for n := start; n < end; n++ {
+			p := n
+			if order != nil {
+				p = order[n]
+			}
+			ci, _ := m.nearest(cc, dataset[p])
+			if points[p] != ci {
+				points[p] = ci
+				changes.Add(1)
+				if lastChanged != nil {
+					lastChanged[p] = iteration
+				}
+			}
+		}
+		if order != nil {
+			return
+		}
+
+		// the chunks get taken in order, so the previous one is in progress
+		if chunk > 0 {
+			<-done[chunk-1]
+		}
+		for p := start; p < end; p++ {
+			ci := points[p]
+			w := 1.0
+			if m.Weights != nil {
+				w = m.Weights[p]
+			}
+			for j, v := range dataset[p].Coordinates() {
+				part[ci*dims+j] += w * v
+			}
+			partTotal[ci] += w
+			if count[ci]++; count[ci] == recenterChunkSize {
+				flush(ci)
+			}
+		}
+	})
+	for ci := range cc {
+		if count[ci] > 0 {
+			flush(ci)
+		}
+	}
+
+	var sizes []int
+	if lean {
+		sizes = memberCounts(k, points)
+	} else {
+		// appending to the member lists while assigning would race with
+		// the lookups of the nearest centers
+		for n := range dataset {
+			p := n
+			if order != nil {
+				p = order[n]
+			}
+			cc[points[p]].Append(dataset[p])
+		}
+	}
+	m.clock.lap(phaseAssignment)
+
+	refilled := m.refillEmptySized(cc, dataset, points, rng, frozen, sizes)
+	if lastChanged != nil {
+		for _, p := range refilled {
+			lastChanged[p] = iteration
+		}
+	}
+	m.clock.lap(phaseReseed)
+	if changes.Load() == 0 && len(refilled) == 0 {
+		return 0, nil
+	}
+	if order != nil || len(refilled) > 0 {
+		m.recenter(cc, dataset, points)
+		m.clock.lap(phaseRecenter)
+		return changes.Load(), refilled
+	}
+
+	for ci := range cc {
+		if (frozen != nil && frozen[ci]) || total[ci] == 0 {
+			continue
+		}
+		center := make(clusters.Coordinates, dims)
+		for j := range center {
+			center[j] = sum[ci*dims+j] / total[ci]
+		}
+		cc[ci].Center = center
+	}
+
+	if m.Center == CenterPrototype {
+		var members [][]int
+		if m.Weights != nil {
+			members = make([][]int, len(cc))
+			for i, ci := range points {
+				members[ci] = append(members[ci], i)
+			}
+		}
+		m.recenterModes(cc, dataset, members, frozen)
+	}
+	if m.Spherical {
+		normalizeCenters(cc, frozen)
+	}
+	m.constrain(cc, frozen)
+	m.clock.lap(phaseRecenter)
+	return changes.Load(), refilled
+}
@@ -0,0 +1,151 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestFusedRecenter(t *testing.T) {
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	weights := make([]float64, 4096)
+	for i := range weights {
+		d = append(d, clusters.Coordinates{rng.Float64(), rng.Float64(), rng.Float64()})
+		weights[i] = rng.Float64()
+	}
+
+	partition := func(fused bool, threads int, weights []float64, shuffle bool) clusters.Clusters {
+		km := New()
+		km.Init = InitKMeansPlusPlus
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.FusedRecenter = fused
+		km.Threads = threads
+		km.Weights = weights
+		km.ShuffleEachIteration = shuffle
+		cc, err := km.Partition(d, 8)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc
+	}
+
+	for _, w := range [][]float64{nil, weights} {
+		for _, shuffle := range []bool{false, true} {
+			exp := partition(false, 1, w, shuffle)
+			for _, threads := range []int{1, 4} {
+				cc := partition(true, threads, w, shuffle)
+				for ci := range exp {
+					if len(cc[ci].Observations) != len(exp[ci].Observations) {
+						t.Errorf("Expected %d members in cluster %d, got %d", len(exp[ci].Observations), ci, len(cc[ci].Observations))
+					}
+					if !reflect.DeepEqual(cc[ci].Center, exp[ci].Center) {
+						t.Errorf("Expected center %v with %d threads, got %v", exp[ci].Center, threads, cc[ci].Center)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFusedRecenterRefill(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{1, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{10, 0},
+	}
+
+	step := func(fused bool) clusters.Clusters {
+		cc := clusters.Clusters{
+			{Center: clusters.Coordinates{1, 0}},
+			{Center: clusters.Coordinates{10, 0}},
+			// never nearest, so it gets refilled
+			{Center: clusters.Coordinates{100, 100}},
+		}
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Refill = RefillLargest
+		km.FusedRecenter = fused
+		if _, err := km.Step(cc, d, make([]int, len(d))); err != nil {
+			t.Fatalf("Unexpected error stepping: %v", err)
+		}
+		return cc
+	}
+
+	exp, cc := step(false), step(true)
+	members := 0
+	for ci := range exp {
+		members += len(exp[ci].Observations)
+		if len(cc[ci].Observations) != len(exp[ci].Observations) || !reflect.DeepEqual(cc[ci].Center, exp[ci].Center) {
+			t.Errorf("Expected cluster %d at %v with %d members, got %v with %d", ci, exp[ci].Center, len(exp[ci].Observations), cc[ci].Center, len(cc[ci].Observations))
+		}
+	}
+	if members != len(d) {
+		t.Errorf("Expected every data point in a single cluster, got %d members", members)
+	}
+
+	// refills in every run, with more clusters than the random seeds fill
+	var many clusters.Observations
+	rng := rand.New(rand.NewSource(randomSeed))
+	for i := 0; i < 64; i++ {
+		many = append(many, clusters.Coordinates{2 + rng.Float64(), 2 + rng.Float64()})
+	}
+	partition := func(fused bool, threads int) Result {
+		km := New()
+		km.Init = InitRandom
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.FusedRecenter = fused
+		km.Threads = threads
+		res, err := km.Fit(many, 48)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return res
+	}
+	want := partition(false, 1)
+	if want.EmptyClusters == 0 {
+		t.Errorf("Expected refilled clusters")
+	}
+	for _, threads := range []int{1, 4} {
+		res := partition(true, threads)
+		if !reflect.DeepEqual(res.Assignment, want.Assignment) {
+			t.Errorf("Expected the assignment of the separate pass with %d threads", threads)
+		}
+		for ci := range want.Clusters {
+			if !reflect.DeepEqual(res.Clusters[ci].Center, want.Clusters[ci].Center) {
+				t.Errorf("Expected center %v with %d threads, got %v", want.Clusters[ci].Center, threads, res.Clusters[ci].Center)
+			}
+		}
+	}
+}
+
+func benchmarkStepFused(fused bool, b *testing.B) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 65536; i++ {
+		d = append(d, clusters.Coordinates{rand.Float64(), rand.Float64()})
+	}
+	cc, err := Kmeans{Init: InitForgy}.seed(16, d, rand.New(rand.NewSource(randomSeed)))
+	if err != nil {
+		b.Fatalf("Unexpected error seeding: %v", err)
+	}
+	assignment := make([]int, len(d))
+
+	km := New()
+	km.Threads = 8
+	km.FusedRecenter = fused
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		// shift every data point, so every step recenters
+		for i := range assignment {
+			assignment[i] = -1
+		}
+		km.step(cc, d, assignment, nil, nil, 0, nil)
+	}
+}
+
+func BenchmarkStepTwoPass(b *testing.B) { benchmarkStepFused(false, b) }
+func BenchmarkStepFused(b *testing.B)   { benchmarkStepFused(true, b) }
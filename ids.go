@@ -0,0 +1,70 @@
+package kmeans
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/k----n/clusters"
+)
+
+// StableIDs returns an ID for each cluster derived from its center rather
+// than its index, so a cluster in roughly the same location keeps its ID
+// across runs and retraining, e.g. to key persisted assignments by. Every
+// coordinate of the center gets quantized to a multiple of granularity,
+// and the ID is the 64-bit FNV-1a hash of the quantized coordinates: centers
+// in the same cell of the grid of that spacing share their ID, while a
+// center which drifts across a cell boundary gets a new one. Choose the
+// granularity well above the drift of the centers between runs and below
+// the distances between them. If several clusters of cc fall into the
+// same cell (or their hashes collide), the one with the lowest center, in
+// lexicographic order of the coordinates, keeps the hash and the others get
+// the next free values in that order, independent of the cluster indices
+func StableIDs(cc clusters.Clusters, granularity float64) ([]uint64, error) {
+	if granularity <= 0 || math.IsNaN(granularity) || math.IsInf(granularity, 1) {
+		return nil, fmt.Errorf("the granularity %f must be positive and finite", granularity)
+	}
+
+	order := make([]int, len(cc))
+	for ci := range order {
+		order[ci] = ci
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ca, cb := cc[order[a]].Center, cc[order[b]].Center
+		for j := 0; j < len(ca) && j < len(cb); j++ {
+			if ca[j] != cb[j] {
+				return ca[j] < cb[j]
+			}
+		}
+		return len(ca) < len(cb)
+	})
+
+	ids := make([]uint64, len(cc))
+	taken := make(map[uint64]struct{}, len(cc))
+	var buf [8]byte
+	for _, ci := range order {
+		h := fnv.New64a()
+		for _, v := range cc[ci].Center {
+			q := math.Round(v / granularity)
+			if q == 0 {
+				// treat -0 and 0 alike
+				q = 0
+			}
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(q))
+			h.Write(buf[:]) //nolint:errcheck // hashes never fail to write
+		}
+
+		id := h.Sum64()
+		for {
+			if _, ok := taken[id]; !ok {
+				break
+			}
+			id++
+		}
+		taken[id] = struct{}{}
+		ids[ci] = id
+	}
+	return ids, nil
+}
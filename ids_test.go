@@ -0,0 +1,44 @@
+package kmeans
+
+import (
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestStableIDs(t *testing.T) {
+	// the first and the last cluster share a cell
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0.1, 5}},
+		{Center: clusters.Coordinates{10.2, -3}},
+		{Center: clusters.Coordinates{-0.2, 4.9}},
+	}
+	ids, err := StableIDs(cc, 1)
+	if err != nil {
+		t.Errorf("Unexpected error computing IDs: %v", err)
+		return
+	}
+	if ids[0] == ids[1] || ids[0] == ids[2] || ids[1] == ids[2] {
+		t.Errorf("Expected unique IDs, got %v", ids)
+	}
+
+	// another run, with the clusters in a different order and slightly
+	// moved, keeps the IDs
+	moved := clusters.Clusters{
+		{Center: clusters.Coordinates{10.3, -3.1}},
+		{Center: clusters.Coordinates{-0.1, 5}},
+		{Center: clusters.Coordinates{0.2, 4.8}},
+	}
+	again, err := StableIDs(moved, 1)
+	if err != nil {
+		t.Errorf("Unexpected error computing IDs: %v", err)
+		return
+	}
+	if again[0] != ids[1] || again[1] != ids[2] || again[2] != ids[0] {
+		t.Errorf("Expected IDs %v of the moved clusters, got %v", []uint64{ids[1], ids[2], ids[0]}, again)
+	}
+
+	if _, err := StableIDs(cc, 0); err == nil {
+		t.Errorf("Expected error computing IDs with zero granularity, got nil")
+	}
+}
@@ -0,0 +1,56 @@
+package kmeans
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/k----n/clusters"
+)
+
+// SegmentImage partitions the pixels of the image into k superpixel-like
+// segments (in the spirit of SLIC), and returns the segment of each pixel,
+// row by row: labels[y*width+x] is the segment of the pixel at (x, y),
+// relative to the bounds of the image. Every pixel becomes a 5-dimensional
+// observation of its position x, y and its 8-bit color components r, g, b
+// (0 to 255, alpha-premultiplied). The spatialWeight is the FeatureWeight
+// of both position dimensions against a weight of 1 for the color ones: at
+// 0 only the colors count, like color quantization, and the larger it gets
+// the more compact and the less color-coherent the segments become. A
+// weight of (c/s)² weighs a spacing of s pixels like a color difference of
+// c. The configured FeatureWeights get replaced, while Weights and
+// InitLabels refer to the pixels in the order of the labels.
+//
+// It's the regular k-means on all pixels, not limited to local search
+// windows like SLIC: every iteration evaluates the distance of each pixel to
+// every center, O(width·height·k), and each pixel takes an observation of
+// its own in memory, in the order of 100 bytes. Megapixel images therefore
+// take considerable time and memory: downscale them first, or fit on a
+// sample of the pixels and assign the rest with Predict
+func (m Kmeans) SegmentImage(img image.Image, k int, spatialWeight float64) (labels []int, err error) {
+	if spatialWeight < 0 || math.IsNaN(spatialWeight) || math.IsInf(spatialWeight, 1) {
+		return nil, fmt.Errorf("the spatial weight %f must be non-negative and finite", spatialWeight)
+	}
+
+	b := img.Bounds()
+	dataset := make(clusters.Observations, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			dataset = append(dataset, clusters.Coordinates{
+				float64(x - b.Min.X),
+				float64(y - b.Min.Y),
+				float64(r >> 8),
+				float64(g >> 8),
+				float64(bl >> 8),
+			})
+		}
+	}
+
+	m.FeatureWeights = []float64{spatialWeight, spatialWeight, 1, 1, 1}
+	res, err := m.partition(dataset, k)
+	if err != nil {
+		return nil, err
+	}
+	return res.assignment, nil
+}
@@ -0,0 +1,63 @@
+package kmeans
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestSegmentImage(t *testing.T) {
+	// two red squares left and right of a blue one
+	img := image.NewRGBA(image.Rect(10, 10, 40, 20))
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 40; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 && x < 30 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Init = InitKMeansPlusPlus
+	km.NInit = 4
+
+	// by color alone, both red squares are a single segment
+	labels, err := km.SegmentImage(img, 2, 0)
+	if err != nil {
+		t.Errorf("Unexpected error segmenting: %v", err)
+		return
+	}
+	if len(labels) != 300 {
+		t.Errorf("Expected 300 labels, got %d", len(labels))
+		return
+	}
+	if labels[0] != labels[29] || labels[0] == labels[15] {
+		t.Errorf("Expected the red squares in one segment apart from the blue one, got %v", labels)
+	}
+
+	// with the positions taken into account, they become separate segments
+	labels, err = km.SegmentImage(img, 3, 1)
+	if err != nil {
+		t.Errorf("Unexpected error segmenting: %v", err)
+		return
+	}
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 30; x++ {
+			if labels[y*30+x] != labels[x/10*10] {
+				t.Errorf("Expected pixel (%d, %d) in segment %d, got %d", x, y, labels[x/10*10], labels[y*30+x])
+				return
+			}
+		}
+	}
+	if labels[0] == labels[10] || labels[10] == labels[20] || labels[0] == labels[20] {
+		t.Errorf("Expected three distinct segments, got %d, %d and %d", labels[0], labels[10], labels[20])
+	}
+
+	if _, err := km.SegmentImage(img, 2, -1); err == nil {
+		t.Errorf("Expected error segmenting with a negative spatial weight, got nil")
+	}
+}
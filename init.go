@@ -0,0 +1,667 @@
+package kmeans
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/k----n/clusters"
+)
+
+// Initializer chooses the initial cluster centers, drawing all random
+// choices from rng. It must return k centers with the dimensions of the
+// dataset
+type Initializer interface {
+	Init(dataset clusters.Observations, k int, rng *rand.Rand) ([]clusters.Coordinates, error)
+}
+
+// InitMethod is one of the built-in initializers
+type InitMethod int
+
+const (
+	// InitRandom places the centers at random coordinates in the unit
+	// hypercube, which is what clusters.New does. It assumes the data set
+	// to be normalized to values between 0.0 and 1.0
+	InitRandom InitMethod = iota
+	// InitForgy picks k distinct random observations of the data set as
+	// centers, which tends to spread the centers out across the data
+	InitForgy
+	// InitRandomPartition randomly assigns each observation to one of the k
+	// clusters and uses the means of those clusters as centers, which
+	// places the centers close to the mean of the entire data set
+	InitRandomPartition
+	// InitKMeansPlusPlus picks the first center at random among the
+	// observations, and each further center among the observations with a
+	// probability proportional to its squared distance to the nearest
+	// center picked so far
+	// See: https://en.wikipedia.org/wiki/K-means%2B%2B
+	InitKMeansPlusPlus
+	// InitBoundingBox spreads the centers evenly along the diagonal of the
+	// data set's bounding box, from its minimum to its maximum coordinates.
+	// It is fully deterministic. Dimensions without any extent place all
+	// centers at their single value
+	InitBoundingBox
+	// InitKMeansParallel is the scalable variant of InitKMeansPlusPlus
+	// (k-means||): it oversamples candidates in a few parallel rounds,
+	// each observation being picked with a probability proportional to its
+	// squared distance to the candidates so far, and then picks k centers
+	// among the candidates by k-means++ weighted with the number of
+	// observations nearest to each. Every parallel task draws from its own
+	// source, seeded from the configured one, so the seeds only depend on
+	// the source of randomness
+	// See: https://arxiv.org/abs/1203.6402
+	InitKMeansParallel
+	// InitQuantile picks the observations at the k quantiles (the middles
+	// of k equally large shares) along the dimension of the highest
+	// variance (weighted by the FeatureWeights, if set) as centers, which
+	// spreads them along the data set's main axis of variation. It is fully
+	// deterministic. Observations with the same value along that dimension
+	// are ordered by their other coordinates, and identical observations
+	// count once, so ties and dimensions with few distinct values still
+	// yield distinct centers, as long as there are k distinct observations
+	InitQuantile
+)
+
+// Init returns k centers chosen by the init method, drawing all random
+// choices from rng
+func (im InitMethod) Init(dataset clusters.Observations, k int, rng *rand.Rand) ([]clusters.Coordinates, error) {
+	cc, err := Kmeans{Init: im}.seed(k, dataset, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	centers := make([]clusters.Coordinates, len(cc))
+	for ci, c := range cc {
+		centers[ci] = c.Center
+	}
+	return centers, nil
+}
+
+// WarmStart is an Initializer seeding the given centers, e.g. the centroids
+// of a previous clustering, so the clusters keep their indices
+type WarmStart []clusters.Coordinates
+
+// Init returns copies of the centers. k must equal their number
+func (ws WarmStart) Init(dataset clusters.Observations, k int, rng *rand.Rand) ([]clusters.Coordinates, error) {
+	if k != len(ws) {
+		return nil, fmt.Errorf("k must equal the number of warm-start centers (%d)", len(ws))
+	}
+	centers := make([]clusters.Coordinates, len(ws))
+	for ci, c := range ws {
+		centers[ci] = append(clusters.Coordinates{}, c...)
+	}
+	return centers, nil
+}
+
+// seed returns k clusters with their centers chosen by the configured
+// initializer, drawing all random choices from rng. Built-in init methods
+// which pick observations as centers only pick among the CandidatePool, if
+// set, and measure distances with the configured metric
+func (m Kmeans) seed(k int, dataset clusters.Observations, rng *rand.Rand) (clusters.Clusters, error) {
+	if len(dataset) == 0 || len(dataset[0].Coordinates()) == 0 {
+		return nil, fmt.Errorf("there must be at least one dimension in the data set")
+	}
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be greater than 0")
+	}
+
+	im, ok := m.Init.(InitMethod)
+	if !ok && m.Init != nil {
+		return m.seedCustom(k, dataset, rng)
+	}
+
+	pool := dataset
+	if m.CandidatePool != nil {
+		pool = make(clusters.Observations, len(m.CandidatePool))
+		for i, p := range m.CandidatePool {
+			pool[i] = dataset[p]
+		}
+	}
+
+	cc := make(clusters.Clusters, k)
+	switch im {
+	case InitRandom:
+		for i := range cc {
+			cc[i].Center = make(clusters.Coordinates, len(dataset[0].Coordinates()))
+			for j := range cc[i].Center {
+				cc[i].Center[j] = rng.Float64()
+			}
+		}
+
+	case InitForgy:
+		for i, p := range rng.Perm(len(pool))[:k] {
+			cc[i].Center = append(clusters.Coordinates{}, pool[p].Coordinates()...)
+		}
+
+	case InitRandomPartition:
+		groups := make([]clusters.Observations, k)
+		for _, o := range dataset {
+			ci := rng.Intn(k)
+			groups[ci] = append(groups[ci], o)
+		}
+		for i, g := range groups {
+			center, err := g.Center()
+			if err != nil {
+				// nothing got assigned to this cluster, fall back to a
+				// random observation
+				center = append(clusters.Coordinates{}, dataset[rng.Intn(len(dataset))].Coordinates()...)
+			}
+			cc[i].Center = center
+		}
+
+	case InitKMeansPlusPlus:
+		for i, p := range kmeansPlusPlus(k, pool, nil, rng, m.distance) {
+			cc[i].Center = append(clusters.Coordinates{}, pool[p].Coordinates()...)
+		}
+
+	case InitKMeansParallel:
+		for i, p := range kmeansParallel(k, pool, rng, m.distance, m.forEach) {
+			cc[i].Center = append(clusters.Coordinates{}, pool[p].Coordinates()...)
+		}
+
+	case InitQuantile:
+		for i, p := range m.quantiles(k, pool) {
+			cc[i].Center = append(clusters.Coordinates{}, pool[p].Coordinates()...)
+		}
+
+	case InitBoundingBox:
+		mins := append(clusters.Coordinates{}, dataset[0].Coordinates()...)
+		maxs := append(clusters.Coordinates{}, dataset[0].Coordinates()...)
+		for _, o := range dataset[1:] {
+			for j, v := range o.Coordinates() {
+				if v < mins[j] {
+					mins[j] = v
+				}
+				if v > maxs[j] {
+					maxs[j] = v
+				}
+			}
+		}
+		for i := range cc {
+			// place the centers in the middle of k equally long segments
+			f := (float64(i) + 0.5) / float64(k)
+			cc[i].Center = make(clusters.Coordinates, len(mins))
+			for j := range mins {
+				cc[i].Center[j] = mins[j] + f*(maxs[j]-mins[j])
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown init method %d", im)
+	}
+
+	return cc, nil
+}
+
+// duplicateSeparation is the CentroidSeparation at or below which
+// seedSeparated considers two centers to coincide, the squared precision of
+// Dedupe
+const duplicateSeparation = 1e-18
+
+// seedSeparated is seed, retrying built-in init methods as configured by
+// InitRetries while they place two centers on the same spot, and falling
+// back to InitKMeansPlusPlus if they keep doing so
+func (m Kmeans) seedSeparated(k int, dataset clusters.Observations, rng *rand.Rand) (clusters.Clusters, error) {
+	cc, err := m.seed(k, dataset, rng)
+	if _, builtin := m.Init.(InitMethod); err != nil || m.InitRetries <= 0 || (m.Init != nil && !builtin) {
+		return cc, err
+	}
+
+	for retries := 0; m.coincide(cc); retries++ {
+		if retries == m.InitRetries {
+			m.Init = InitKMeansPlusPlus
+			return m.seed(k, dataset, rng)
+		}
+		if cc, err = m.seed(k, dataset, rng); err != nil {
+			return nil, err
+		}
+	}
+	return cc, nil
+}
+
+// coincide returns whether any two centers of the clusters are at most
+// duplicateSeparation apart
+func (m Kmeans) coincide(cc clusters.Clusters) bool {
+	for _, s := range m.CentroidSeparation(cc) {
+		if s <= duplicateSeparation {
+			return true
+		}
+	}
+	return false
+}
+
+// quantiles returns the indices of the observations at the k quantiles of
+// the distinct observations along the dimension of the highest (weighted)
+// variance. With fewer than k distinct observations, some of them get
+// picked repeatedly
+func (m Kmeans) quantiles(k int, dataset clusters.Observations) []int {
+	dims := len(dataset[0].Coordinates())
+	means := m.grandMean(dataset, nil)
+	variances := make([]float64, dims)
+	for _, o := range dataset {
+		for j, v := range o.Coordinates() {
+			variances[j] += (v - means[j]) * (v - means[j])
+		}
+	}
+	dim := 0
+	for j := range variances {
+		if m.FeatureWeights != nil {
+			variances[j] *= m.FeatureWeights[j]
+		}
+		if variances[j] > variances[dim] {
+			dim = j
+		}
+	}
+
+	order := make([]int, len(dataset))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ca, cb := dataset[order[a]].Coordinates(), dataset[order[b]].Coordinates()
+		if ca[dim] != cb[dim] {
+			return ca[dim] < cb[dim]
+		}
+		for j := range ca {
+			if ca[j] != cb[j] {
+				return ca[j] < cb[j]
+			}
+		}
+		return false
+	})
+
+	// identical observations are adjacent now
+	var distinct []int
+	var prev, key []byte
+	for _, i := range order {
+		key = dedupeKey(key[:0], dataset[i])
+		if distinct == nil || string(key) != string(prev) {
+			distinct = append(distinct, i)
+			prev = append(prev[:0], key...)
+		}
+	}
+
+	picked := make([]int, k)
+	for q := range picked {
+		picked[q] = distinct[(2*q+1)*len(distinct)/(2*k)]
+	}
+	return picked
+}
+
+// seedCustom returns k clusters with their centers chosen by a custom
+// initializer
+func (m Kmeans) seedCustom(k int, dataset clusters.Observations, rng *rand.Rand) (clusters.Clusters, error) {
+	var centers []clusters.Coordinates
+	var err error
+	if di, ok := m.Init.(DensityInit); ok {
+		centers, err = di.init(dataset, k, rng, m.distance)
+	} else {
+		centers, err = m.Init.Init(dataset, k, rng)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(centers) != k {
+		return nil, fmt.Errorf("the initializer must return k centers, got %d", len(centers))
+	}
+
+	cc := make(clusters.Clusters, k)
+	for ci, c := range centers {
+		if len(c) != len(dataset[0].Coordinates()) {
+			return nil, fmt.Errorf("center %d of the initializer must have %d dimensions", ci, len(dataset[0].Coordinates()))
+		}
+		cc[ci].Center = append(clusters.Coordinates{}, c...)
+	}
+	return cc, nil
+}
+
+// kmeansPlusPlus returns the indices of k observations picked by k-means++
+// seeding, drawing all random choices from rng and measuring squared
+// distances with distance. Unless weights is nil, the probability of each
+// observation to get picked is multiplied by its weight
+func kmeansPlusPlus(k int, dataset clusters.Observations, weights []float64, rng *rand.Rand, distance func(clusters.Observation, clusters.Coordinates) float64) []int {
+	picked := []int{rng.Intn(len(dataset))}
+
+	// squared distance of each observation to its nearest picked center
+	dist := make([]float64, len(dataset))
+	for i, o := range dataset {
+		dist[i] = distance(o, dataset[picked[0]].Coordinates())
+	}
+	weighted := func(i int) float64 {
+		if weights != nil {
+			return weights[i] * dist[i]
+		}
+		return dist[i]
+	}
+
+	for len(picked) < k {
+		var sum float64
+		for i := range dist {
+			sum += weighted(i)
+		}
+
+		p := len(dataset) - 1
+		if sum == 0 {
+			// all observations coincide with a center
+			p = rng.Intn(len(dataset))
+		} else {
+			r := rng.Float64() * sum
+			for i := range dist {
+				d := weighted(i)
+				if r < d {
+					p = i
+					break
+				}
+				r -= d
+			}
+		}
+		picked = append(picked, p)
+
+		c := dataset[p].Coordinates()
+		for i, o := range dataset {
+			if d := distance(o, c); d < dist[i] {
+				dist[i] = d
+			}
+		}
+	}
+
+	return picked
+}
+
+// seedChunkSize is the number of observations processed by a single task
+// when seeding in parallel
+const seedChunkSize = 1024
+
+// kmeansParallelRounds is the number of oversampling rounds of k-means||,
+// which the paper found to be sufficient in practice
+const kmeansParallelRounds = 5
+
+// kmeansParallel returns the indices of k observations picked by k-means||
+// seeding, measuring squared distances with distance. The observations get
+// processed in chunks of seedChunkSize, each chunk drawing from its own
+// source seeded sequentially from rng
+func kmeansParallel(k int, dataset clusters.Observations, rng *rand.Rand, distance func(clusters.Observation, clusters.Coordinates) float64, forEach func(n int, fn func(i int))) []int {
+	chunks := (len(dataset) + seedChunkSize - 1) / seedChunkSize
+	eachChunk := func(fn func(chunk, start, end int)) {
+		forEach(chunks, func(chunk int) {
+			end := (chunk + 1) * seedChunkSize
+			if end > len(dataset) {
+				end = len(dataset)
+			}
+			fn(chunk, chunk*seedChunkSize, end)
+		})
+	}
+
+	picked := []int{rng.Intn(len(dataset))}
+	isPicked := make([]bool, len(dataset))
+	isPicked[picked[0]] = true
+
+	// squared distance of each observation to its nearest candidate, and
+	// the index of that candidate in picked
+	dist := make([]float64, len(dataset))
+	nearest := make([]int, len(dataset))
+	update := func(added []int) {
+		offset := len(picked) - len(added)
+		forEach(len(dataset), func(i int) {
+			for n, p := range added {
+				if d := distance(dataset[i], dataset[p].Coordinates()); n+offset == 0 || d < dist[i] {
+					dist[i], nearest[i] = d, n+offset
+				}
+			}
+		})
+	}
+	update(picked)
+
+	oversampling := float64(2 * k)
+	for round := 0; round < kmeansParallelRounds; round++ {
+		sums := make([]float64, chunks)
+		eachChunk(func(chunk, start, end int) {
+			for i := start; i < end; i++ {
+				sums[chunk] += dist[i]
+			}
+		})
+		var cost float64
+		for _, s := range sums {
+			cost += s
+		}
+		if cost == 0 {
+			// all observations coincide with a candidate
+			break
+		}
+
+		seeds := make([]int64, chunks)
+		for chunk := range seeds {
+			seeds[chunk] = rng.Int63()
+		}
+		sampled := make([][]int, chunks)
+		eachChunk(func(chunk, start, end int) {
+			crng := rand.New(rand.NewSource(seeds[chunk])) //nolint:gosec // math/rand is good enough for this
+			for i := start; i < end; i++ {
+				if crng.Float64()*cost < oversampling*dist[i] {
+					sampled[chunk] = append(sampled[chunk], i)
+				}
+			}
+		})
+
+		var added []int
+		for _, s := range sampled {
+			added = append(added, s...)
+		}
+		for _, p := range added {
+			isPicked[p] = true
+		}
+		picked = append(picked, added...)
+		update(added)
+	}
+
+	// with too few candidates, fill up with random observations
+	for _, p := range rng.Perm(len(dataset)) {
+		if len(picked) >= k {
+			break
+		}
+		if !isPicked[p] {
+			isPicked[p] = true
+			picked = append(picked, p)
+			update([]int{p})
+		}
+	}
+
+	weights := make([]float64, len(picked))
+	for _, n := range nearest {
+		weights[n]++
+	}
+	candidates := make(clusters.Observations, len(picked))
+	for n, p := range picked {
+		candidates[n] = dataset[p]
+	}
+
+	indices := kmeansPlusPlus(k, candidates, weights, rng, distance)
+	for i, n := range indices {
+		indices[i] = picked[n]
+	}
+	return indices
+}
+
+// defaultDensityNeighbors is the number of nearest neighbors DensityInit
+// estimates the density from by default
+const defaultDensityNeighbors = 5
+
+// DensityInit is an Initializer for data of varying density, which picks
+// seeds in dense regions, passing over outliers. The sparsity of an
+// observation is estimated by the squared distance to its Neighbors-th
+// nearest neighbor. The first seed is the densest observation, and each
+// further seed the observation farthest from the seeds picked so far
+// relative to its sparsity (plus the median sparsity, which keeps the ratio
+// bounded for duplicates). The source of randomness is only used to break
+// ties, so the seeds are reproducible. Seeding a Kmeans, it measures
+// distances with the configured metric, otherwise squared Euclidean ones.
+// Estimating the densities takes time quadratic in the size of the data set
+type DensityInit struct {
+	// Neighbors is the number of nearest neighbors the density of an
+	// observation is estimated from (defaults to 5)
+	Neighbors int
+}
+
+// Init returns k seeds picked among the densest observations
+func (di DensityInit) Init(dataset clusters.Observations, k int, rng *rand.Rand) ([]clusters.Coordinates, error) {
+	return di.init(dataset, k, rng, Kmeans{}.distance)
+}
+
+// init is Init measuring squared distances with distance
+func (di DensityInit) init(dataset clusters.Observations, k int, rng *rand.Rand, distance func(clusters.Observation, clusters.Coordinates) float64) ([]clusters.Coordinates, error) {
+	if k <= 0 || k > len(dataset) {
+		return nil, fmt.Errorf("k is out of bounds (must be between 1 and the size of the data set)")
+	}
+	neighbors := di.Neighbors
+	if neighbors <= 0 {
+		neighbors = defaultDensityNeighbors
+	}
+	if neighbors > len(dataset)-1 {
+		neighbors = len(dataset) - 1
+	}
+
+	// squared distance of each observation to its Neighbors-th nearest
+	// neighbor, the smaller the denser
+	kdist := make([]float64, len(dataset))
+	if neighbors > 0 {
+		nearest := make([]float64, 0, neighbors)
+		for i, o := range dataset {
+			nearest = nearest[:0]
+			for j, p := range dataset {
+				if i == j {
+					continue
+				}
+				d := distance(o, p.Coordinates())
+				switch {
+				case len(nearest) < neighbors:
+					nearest = append(nearest, d)
+				case d < nearest[neighbors-1]:
+					nearest[neighbors-1] = d
+				default:
+					continue
+				}
+				// keep the nearest distances sorted in ascending order
+				for n := len(nearest) - 1; n > 0 && nearest[n] < nearest[n-1]; n-- {
+					nearest[n], nearest[n-1] = nearest[n-1], nearest[n]
+				}
+			}
+			kdist[i] = nearest[neighbors-1]
+		}
+	}
+
+	sorted := append([]float64{}, kdist...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	// pick returns the unpicked observation with the highest score,
+	// breaking ties randomly
+	picked := make([]bool, len(dataset))
+	pick := func(score func(i int) float64) int {
+		var ties []int
+		var best float64
+		for i := range dataset {
+			if picked[i] {
+				continue
+			}
+			s := score(i)
+			switch {
+			case len(ties) == 0 || s > best:
+				ties, best = append(ties[:0], i), s
+			case s == best:
+				ties = append(ties, i)
+			}
+		}
+		p := ties[0]
+		if len(ties) > 1 {
+			p = ties[rng.Intn(len(ties))]
+		}
+		picked[p] = true
+		return p
+	}
+
+	// squared distance of each observation to its nearest seed
+	dist := make([]float64, len(dataset))
+	seeds := make([]clusters.Coordinates, 0, k)
+	add := func(p int) {
+		c := append(clusters.Coordinates{}, dataset[p].Coordinates()...)
+		for i, o := range dataset {
+			if d := distance(o, c); len(seeds) == 0 || d < dist[i] {
+				dist[i] = d
+			}
+		}
+		seeds = append(seeds, c)
+	}
+
+	add(pick(func(i int) float64 {
+		return -kdist[i]
+	}))
+	for len(seeds) < k {
+		add(pick(func(i int) float64 {
+			if dist[i] == 0 {
+				return 0
+			}
+			return dist[i] / (kdist[i] + median)
+		}))
+	}
+
+	return seeds, nil
+}
+
+// FarthestFirst returns the indices of k observations spread out by
+// farthest-first traversal: starting from a random observation, drawn from
+// the source of randomness, it repeatedly picks the observation farthest
+// from all picked so far, by the configured metric. Ties go to the lowest
+// index, so the picks only depend on the source of randomness, which also
+// makes them a reproducible choice of seeds. Once all remaining observations
+// coincide with picked ones, duplicates get picked by index
+func (m Kmeans) FarthestFirst(dataset clusters.Observations, k int) ([]int, error) {
+	if len(dataset) == 0 || len(dataset[0].Coordinates()) == 0 {
+		return nil, fmt.Errorf("there must be at least one dimension in the data set")
+	}
+	if k <= 0 || k > len(dataset) {
+		return nil, fmt.Errorf("k is out of bounds (must be between 1 and the size of the data set)")
+	}
+	if err := m.checkMetric(dataset); err != nil {
+		return nil, err
+	}
+
+	picked := []int{m.rand().Intn(len(dataset))}
+	// distance of each observation to its nearest pick, -1 once picked
+	dist := make([]float64, len(dataset))
+	chunks := (len(dataset) + seedChunkSize - 1) / seedChunkSize
+	farthest := make([]int, chunks)
+
+	for len(picked) < k {
+		c := dataset[picked[len(picked)-1]].Coordinates()
+		dist[picked[len(picked)-1]] = -1
+		m.forEach(chunks, func(chunk int) {
+			end := (chunk + 1) * seedChunkSize
+			if end > len(dataset) {
+				end = len(dataset)
+			}
+			farthest[chunk] = -1
+			for i := chunk * seedChunkSize; i < end; i++ {
+				if dist[i] < 0 {
+					continue
+				}
+				if d := m.distance(dataset[i], c); len(picked) == 1 || d < dist[i] {
+					dist[i] = d
+				}
+				if farthest[chunk] < 0 || dist[i] > dist[farthest[chunk]] {
+					farthest[chunk] = i
+				}
+			}
+		})
+
+		// merge the chunks in order, so ties go to the lowest index
+		p := -1
+		for _, f := range farthest {
+			if f >= 0 && (p < 0 || dist[f] > dist[p]) {
+				p = f
+			}
+		}
+		picked = append(picked, p)
+	}
+
+	return picked, nil
+}
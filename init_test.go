@@ -0,0 +1,454 @@
+package kmeans
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestInitMethods(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 64; i++ {
+		d = append(d, clusters.Coordinates{float64(i) + 2, float64(i) * 2})
+	}
+	rng := rand.New(rand.NewSource(randomSeed))
+
+	cc, err := Kmeans{Init: InitForgy}.seed(4, d, rng)
+	if err != nil {
+		t.Errorf("Unexpected error seeding: %v", err)
+		return
+	}
+	seen := make(map[float64]bool)
+	for _, c := range cc {
+		if c.Center[1] != (c.Center[0]-2)*2 || seen[c.Center[0]] {
+			t.Errorf("Expected distinct observations as centers, got %v", c.Center)
+		}
+		seen[c.Center[0]] = true
+	}
+
+	cc, err = Kmeans{Init: InitRandomPartition}.seed(4, d, rng)
+	if err != nil {
+		t.Errorf("Unexpected error seeding: %v", err)
+		return
+	}
+	for _, c := range cc {
+		// the means of random partitions are close to the global mean
+		if c.Center[0] < 16 || c.Center[0] > 50 {
+			t.Errorf("Expected center close to the global mean, got %v", c.Center)
+		}
+	}
+
+	cc, err = Kmeans{Init: InitRandom}.seed(4, d, rng)
+	if err != nil {
+		t.Errorf("Unexpected error seeding: %v", err)
+		return
+	}
+	for _, c := range cc {
+		if c.Center[0] < 0 || c.Center[0] >= 1 || c.Center[1] < 0 || c.Center[1] >= 1 {
+			t.Errorf("Expected center in the unit square, got %v", c.Center)
+		}
+	}
+
+	if _, err := (Kmeans{Init: InitMethod(-1)}).seed(4, d, rng); err == nil {
+		t.Errorf("Expected error seeding with an unknown init method, got nil")
+	}
+}
+
+func TestKMeansPlusPlusReproducible(t *testing.T) {
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		d = append(d, clusters.Coordinates{rng.Float64(), rng.Float64()})
+	}
+
+	seed := func() clusters.Clusters {
+		cc, err := Kmeans{Init: InitKMeansPlusPlus}.seed(8, d, rand.New(rand.NewSource(randomSeed)))
+		if err != nil {
+			t.Fatalf("Unexpected error seeding: %v", err)
+		}
+		return cc
+	}
+	c1, c2 := seed(), seed()
+	for i := range c1 {
+		if !reflect.DeepEqual(c1[i].Center, c2[i].Center) {
+			t.Errorf("Expected identical seeds for the same seed, got %v and %v", c1[i].Center, c2[i].Center)
+		}
+	}
+
+	// k-means++ never picks the same observation twice while there are
+	// observations left with a positive distance
+	seen := make(map[string]bool)
+	for _, c := range c1 {
+		key := fmt.Sprint(c.Center)
+		if seen[key] {
+			t.Errorf("Expected distinct seeds, got %v twice", c.Center)
+		}
+		seen[key] = true
+	}
+
+	partition := func() clusters.Clusters {
+		km := New()
+		km.Init = InitKMeansPlusPlus
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		cc, err := km.Partition(d, 8)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc
+	}
+	c1, c2 = partition(), partition()
+	for i := range c1 {
+		if !reflect.DeepEqual(c1[i].Center, c2[i].Center) {
+			t.Errorf("Expected identical centers for the same seed, got %v and %v", c1[i].Center, c2[i].Center)
+		}
+	}
+}
+
+func TestCandidatePool(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 64; i++ {
+		d = append(d, clusters.Coordinates{float64(i), 0})
+	}
+	pool := []int{3, 17, 42, 63}
+	approved := map[float64]bool{3: true, 17: true, 42: true, 63: true}
+
+	for _, im := range []InitMethod{InitForgy, InitKMeansPlusPlus, InitKMeansParallel} {
+		cc, err := Kmeans{Init: im, CandidatePool: pool}.seed(3, d, rand.New(rand.NewSource(randomSeed)))
+		if err != nil {
+			t.Errorf("Unexpected error seeding: %v", err)
+			return
+		}
+		for _, c := range cc {
+			if !approved[c.Center[0]] {
+				t.Errorf("Expected init method %d to only pick candidates, got %v", im, c.Center)
+			}
+		}
+	}
+
+	km := New()
+	km.Init = InitForgy
+	km.CandidatePool = pool
+	if _, err := km.Partition(d, 5); err == nil {
+		t.Errorf("Expected error partitioning with fewer candidates than k, got nil")
+	}
+	km.CandidatePool = []int{0, 64}
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with out of bounds candidates, got nil")
+	}
+}
+
+func TestInitBoundingBox(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 5, 10},
+		clusters.Coordinates{4, 5, -10},
+		clusters.Coordinates{2, 5, 0},
+	}
+
+	cc, err := Kmeans{Init: InitBoundingBox}.seed(2, d, nil)
+	if err != nil {
+		t.Errorf("Unexpected error seeding: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(cc[0].Center, clusters.Coordinates{1, 5, -5}) ||
+		!reflect.DeepEqual(cc[1].Center, clusters.Coordinates{3, 5, 5}) {
+		t.Errorf("Expected centers [1 5 -5] and [3 5 5], got %v and %v", cc[0].Center, cc[1].Center)
+	}
+}
+
+func TestKMeansParallelReproducible(t *testing.T) {
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	for i := 0; i < 4096; i++ {
+		d = append(d, clusters.Coordinates{rng.Float64(), rng.Float64()})
+	}
+
+	// the seeds only depend on the source of randomness, not on the number
+	// of threads
+	var c1 clusters.Clusters
+	for _, threads := range []int{1, 2, 8} {
+		km := Kmeans{Init: InitKMeansParallel, Threads: threads}
+		cc, err := km.seed(16, d, rand.New(rand.NewSource(randomSeed)))
+		if err != nil {
+			t.Errorf("Unexpected error seeding: %v", err)
+			return
+		}
+		if c1 == nil {
+			c1 = cc
+		}
+		if !reflect.DeepEqual(cc, c1) {
+			t.Errorf("Expected identical seeds with %d threads, got %v and %v", threads, c1, cc)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range c1 {
+		key := fmt.Sprint(c.Center)
+		if seen[key] {
+			t.Errorf("Expected distinct seeds, got %v twice", c.Center)
+		}
+		seen[key] = true
+	}
+
+	// too few distinct candidates get filled up with random observations
+	dup := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{0, 0},
+	}
+	if cc, err := (Kmeans{Init: InitKMeansParallel}).seed(3, dup, rng); err != nil || len(cc) != 3 {
+		t.Errorf("Expected 3 seeds, got %v (%v)", cc, err)
+	}
+}
+
+// anchors is an initializer seeding fixed centers
+type anchors []clusters.Coordinates
+
+func (a anchors) Init(dataset clusters.Observations, k int, rng *rand.Rand) ([]clusters.Coordinates, error) {
+	return a, nil
+}
+
+func TestInitializer(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 64; i++ {
+		d = append(d, clusters.Coordinates{float64(i%2) * 10, float64(i) / 64.0})
+	}
+
+	centers, err := InitForgy.Init(d, 3, rand.New(rand.NewSource(randomSeed)))
+	if err != nil || len(centers) != 3 {
+		t.Errorf("Expected 3 centers from the built-in initializer, got %v (%v)", centers, err)
+	}
+
+	// the custom seeds determine the order of the clusters
+	km := New()
+	km.Init = anchors{{10, 0.5}, {0, 0.5}}
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if cc[0].Center[0] != 10 || cc[1].Center[0] != 0 {
+		t.Errorf("Expected the clusters to start from the custom seeds, got %v and %v", cc[0].Center, cc[1].Center)
+	}
+
+	if _, err := km.Partition(d, 3); err == nil {
+		t.Errorf("Expected error partitioning with too few custom seeds, got nil")
+	}
+	km.Init = anchors{{10}, {0}}
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with custom seeds of mismatching dimensions, got nil")
+	}
+}
+
+func TestDensityInit(t *testing.T) {
+	// three blobs of different density and a few outliers between them
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	blobs := []clusters.Coordinates{{0, 0}, {20, 0}, {0, 20}}
+	for b, c := range blobs {
+		spread := float64(b+1) * 0.5
+		for i := 0; i < 20; i++ {
+			d = append(d, clusters.Coordinates{c[0] + rng.NormFloat64()*spread, c[1] + rng.NormFloat64()*spread})
+		}
+	}
+	d = append(d, clusters.Coordinates{40, 40}, clusters.Coordinates{-30, 10}, clusters.Coordinates{10, -35})
+
+	seeds, err := DensityInit{}.Init(d, 3, rand.New(rand.NewSource(randomSeed)))
+	if err != nil {
+		t.Errorf("Unexpected error seeding: %v", err)
+		return
+	}
+	seen := make(map[int]bool)
+	for _, s := range seeds {
+		b := -1
+		for bi, c := range blobs {
+			if s.Distance(c) < 25 {
+				b = bi
+			}
+		}
+		if b < 0 || seen[b] {
+			t.Errorf("Expected a seed in each blob, got %v", seeds)
+		}
+		seen[b] = true
+	}
+
+	// the source of randomness only breaks ties
+	again, _ := DensityInit{}.Init(d, 3, rand.New(rand.NewSource(randomSeed+1)))
+	if !reflect.DeepEqual(seeds, again) {
+		t.Errorf("Expected identical seeds, got %v and %v", seeds, again)
+	}
+
+	dup := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{1, 1},
+	}
+	if seeds, err := (DensityInit{Neighbors: 1}).Init(dup, 2, rand.New(rand.NewSource(randomSeed))); err != nil ||
+		!reflect.DeepEqual(seeds, []clusters.Coordinates{{0, 0}, {1, 1}}) {
+		t.Errorf("Expected seeds [0 0] and [1 1], got %v (%v)", seeds, err)
+	}
+	if _, err := (DensityInit{}).Init(dup, 4, rand.New(rand.NewSource(randomSeed))); err == nil {
+		t.Errorf("Expected error seeding more clusters than observations, got nil")
+	}
+
+	km := New()
+	km.Init = DensityInit{Neighbors: 3}
+	if cc, err := km.Partition(d, 3); err != nil || len(cc) != 3 {
+		t.Errorf("Expected 3 clusters, got %v (%v)", cc, err)
+	}
+
+	// seeding a Kmeans measures the configured metric, here ignoring the
+	// second dimension
+	km.FeatureWeights = []float64{1, 0}
+	projected := make(clusters.Observations, len(d))
+	for i, o := range d {
+		projected[i] = clusters.Coordinates{o.Coordinates()[0], 0}
+	}
+	exp, _ := DensityInit{Neighbors: 3}.Init(projected, 3, rand.New(rand.NewSource(randomSeed)))
+	cc, err := km.seed(3, d, rand.New(rand.NewSource(randomSeed)))
+	if err != nil {
+		t.Errorf("Unexpected error seeding: %v", err)
+		return
+	}
+	for ci := range cc {
+		if cc[ci].Center[0] != exp[ci][0] {
+			t.Errorf("Expected seeds along %v, got %v", exp, cc)
+			break
+		}
+	}
+}
+
+func TestFarthestFirst(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 4096; i++ {
+		d = append(d, clusters.Coordinates{float64(i % 64), float64(i / 64)})
+	}
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Threads = 4
+	picked, err := km.FarthestFirst(d, 5)
+	if err != nil {
+		t.Errorf("Unexpected error picking: %v", err)
+		return
+	}
+	// each pick is at least as far from the previous picks as any other
+	// observation
+	for n := 1; n < len(picked); n++ {
+		nearest := func(i int) float64 {
+			dist := -1.0
+			for _, p := range picked[:n] {
+				if d := d[i].Distance(d[p].Coordinates()); dist < 0 || d < dist {
+					dist = d
+				}
+			}
+			return dist
+		}
+		for i := range d {
+			if nearest(i) > nearest(picked[n]) {
+				t.Errorf("Expected pick %d to be farthest from the previous ones, got %v", n, picked)
+				return
+			}
+		}
+	}
+
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Threads = 1
+	if again, _ := km.FarthestFirst(d, 5); !reflect.DeepEqual(picked, again) {
+		t.Errorf("Expected identical picks for the same seed, got %v and %v", picked, again)
+	}
+
+	dup := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{1, 1},
+		clusters.Coordinates{0, 0},
+	}
+	if picked, err := km.FarthestFirst(dup, 3); err != nil || len(picked) != 3 || picked[0] == picked[1] || picked[1] == picked[2] || picked[0] == picked[2] {
+		t.Errorf("Expected 3 distinct indices, got %v (%v)", picked, err)
+	}
+	if _, err := km.FarthestFirst(dup, 4); err == nil {
+		t.Errorf("Expected error picking more observations than available, got nil")
+	}
+}
+
+func TestInitRetries(t *testing.T) {
+	// Forgy almost always picks the duplicates
+	var d clusters.Observations
+	for i := 0; i < 200; i++ {
+		d = append(d, clusters.Coordinates{0, 0})
+	}
+	d = append(d, clusters.Coordinates{1, 0}, clusters.Coordinates{0, 1})
+
+	km := New()
+	km.Init = InitForgy
+	rng := rand.New(rand.NewSource(randomSeed))
+	cc, err := km.seedSeparated(3, d, rng)
+	if err != nil {
+		t.Errorf("Unexpected error seeding: %v", err)
+		return
+	}
+	if !km.coincide(cc) {
+		t.Errorf("Expected coinciding centers without retries, got %v", cc)
+	}
+
+	for _, retries := range []int{1, 1000} {
+		km.InitRetries = retries
+		for i := 0; i < 10; i++ {
+			cc, err := km.seedSeparated(3, d, rng)
+			if err != nil {
+				t.Errorf("Unexpected error seeding: %v", err)
+				return
+			}
+			if km.coincide(cc) {
+				t.Errorf("Expected separate centers with %d retries, got %v", retries, cc)
+			}
+		}
+	}
+}
+
+func TestInitQuantile(t *testing.T) {
+	// spread along the second dimension, with ties in it
+	var d clusters.Observations
+	for i := 0; i < 40; i++ {
+		d = append(d, clusters.Coordinates{float64(i % 2), float64(i / 4 * 10)})
+	}
+
+	cc, err := Kmeans{Init: InitQuantile}.seed(4, d, nil)
+	if err != nil {
+		t.Errorf("Unexpected error seeding: %v", err)
+		return
+	}
+	// 20 distinct observations, ordered by the second dimension first
+	exp := []clusters.Coordinates{{0, 10}, {1, 30}, {0, 60}, {1, 80}}
+	for ci, c := range cc {
+		if !reflect.DeepEqual(c.Center, exp[ci]) {
+			t.Errorf("Expected center %v, got %v", exp[ci], c.Center)
+		}
+	}
+
+	// two distinct values along the dimension of the highest variance
+	// still yield three distinct seeds
+	low := clusters.Observations{
+		clusters.Coordinates{5, 0},
+		clusters.Coordinates{5, 0},
+		clusters.Coordinates{0, 0.1},
+		clusters.Coordinates{0, 0.2},
+		clusters.Coordinates{0, 0.3},
+	}
+	cc, _ = Kmeans{Init: InitQuantile}.seed(3, low, nil)
+	seen := make(map[string]bool)
+	for _, c := range cc {
+		if seen[fmt.Sprint(c.Center)] {
+			t.Errorf("Expected distinct centers, got %v", cc)
+		}
+		seen[fmt.Sprint(c.Center)] = true
+	}
+
+	km := New()
+	km.Init = InitQuantile
+	if cc, err := km.Partition(d, 4); err != nil || len(cc) != 4 {
+		t.Errorf("Expected 4 clusters, got %v (%v)", cc, err)
+	}
+}
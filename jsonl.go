@@ -0,0 +1,91 @@
+package kmeans
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/k----n/clusters"
+)
+
+// JSONLReader reads observations from JSON Lines, one array of numbers per
+// line, e.g. [0.5, 1, -2]. All arrays must be of the same, non-zero length.
+// Blank lines are skipped. Its Next method can be passed to AssignStream,
+// to assign a large file without loading it into memory; check Err once
+// the stream ran dry, like with a bufio.Scanner
+type JSONLReader struct {
+	r *bufio.Reader
+	// number of the last line read
+	line int
+	// length of the arrays, once the first one got read
+	dims int
+	err  error
+}
+
+// NewJSONLReader returns a JSONLReader reading from r
+func NewJSONLReader(r io.Reader) *JSONLReader {
+	return &JSONLReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the observation of the next line. It returns false at the
+// end of the input, or on the first error, which Err reports
+func (jr *JSONLReader) Next() (clusters.Observation, bool) {
+	for jr.err == nil {
+		b, err := jr.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			jr.err = err
+			return nil, false
+		}
+		if len(b) == 0 && err == io.EOF {
+			return nil, false
+		}
+		jr.line++
+
+		if b = bytes.TrimSpace(b); len(b) == 0 {
+			continue
+		}
+		var c clusters.Coordinates
+		if perr := json.Unmarshal(b, &c); perr != nil {
+			jr.err = fmt.Errorf("line %d: %v", jr.line, perr)
+			return nil, false
+		}
+		switch {
+		case len(c) == 0:
+			jr.err = fmt.Errorf("line %d: there must be at least one dimension", jr.line)
+			return nil, false
+		case jr.dims == 0:
+			jr.dims = len(c)
+		case len(c) != jr.dims:
+			jr.err = fmt.Errorf("line %d: expected %d dimensions, got %d", jr.line, jr.dims, len(c))
+			return nil, false
+		}
+		return c, true
+	}
+	return nil, false
+}
+
+// Err returns the first error encountered by Next, or nil at the end of the
+// input
+func (jr *JSONLReader) Err() error {
+	return jr.err
+}
+
+// LoadJSONL reads all observations of JSON Lines from r, as described by
+// JSONLReader
+func LoadJSONL(r io.Reader) (clusters.Observations, error) {
+	jr := NewJSONLReader(r)
+	var dataset clusters.Observations
+	for {
+		o, ok := jr.Next()
+		if !ok {
+			break
+		}
+		dataset = append(dataset, o)
+	}
+	if err := jr.Err(); err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
@@ -0,0 +1,54 @@
+package kmeans
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestLoadJSONL(t *testing.T) {
+	d, err := LoadJSONL(strings.NewReader("[0, 1]\n\n[2.5, -3]\r\n[1e2, 4]"))
+	if err != nil {
+		t.Errorf("Unexpected error loading: %v", err)
+		return
+	}
+	exp := clusters.Observations{
+		clusters.Coordinates{0, 1},
+		clusters.Coordinates{2.5, -3},
+		clusters.Coordinates{100, 4},
+	}
+	if !reflect.DeepEqual(d, exp) {
+		t.Errorf("Expected observations %v, got %v", exp, d)
+	}
+
+	for input, msg := range map[string]string{
+		"[0, 1]\n[1, 2, 3]\n":  "line 2: expected 2 dimensions, got 3",
+		"[0, 1]\n\n[1, \"a\"]": "line 3: ",
+		"[]":                   "line 1: there must be at least one dimension",
+	} {
+		if _, err := LoadJSONL(strings.NewReader(input)); err == nil || !strings.HasPrefix(err.Error(), msg) {
+			t.Errorf("Expected error %q loading %q, got %v", msg, input, err)
+		}
+	}
+}
+
+func TestJSONLAssignStream(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}},
+		{Center: clusters.Coordinates{1, 1}},
+	}
+	jr := NewJSONLReader(strings.NewReader("[0.1, 0.2]\n[0.9, 0.7]\n[0.3, 0.1]\n"))
+
+	var assignment []int
+	New().AssignStream(cc, jr.Next, func(index, ci int) {
+		assignment = append(assignment, ci)
+	})
+	if err := jr.Err(); err != nil {
+		t.Errorf("Unexpected error streaming: %v", err)
+	}
+	if !reflect.DeepEqual(assignment, []int{0, 1, 0}) {
+		t.Errorf("Expected assignment [0 1 0], got %v", assignment)
+	}
+}
@@ -0,0 +1,118 @@
+package kmeans
+
+import (
+	"sort"
+
+	"github.com/k----n/clusters"
+)
+
+// centroidIndex is a k-d tree over the centers of the clusters, for finding
+// the nearest center of a data point without measuring the distance to all
+// of them. It's exact for the (weighted) squared Euclidean distance
+type centroidIndex struct {
+	centers []clusters.Coordinates
+	nodes   []kdNode
+	// weights of the dimensions, nil if they weigh equally
+	weights []float64
+}
+
+// kdNode is a center of the k-d tree, splitting the space of its subtrees
+// at its coordinate in dimension dim: the centers of the left subtree lie at
+// or below it, the ones of the right subtree at or above it
+type kdNode struct {
+	ci          int
+	dim         int
+	left, right int
+}
+
+// newCentroidIndex builds a k-d tree over the centers of the clusters,
+// splitting each subtree at the median of the dimension along which its
+// centers spread the most
+func newCentroidIndex(cc clusters.Clusters, weights []float64) *centroidIndex {
+	t := &centroidIndex{
+		centers: make([]clusters.Coordinates, len(cc)),
+		nodes:   make([]kdNode, 0, len(cc)),
+		weights: weights,
+	}
+	order := make([]int, len(cc))
+	for ci, c := range cc {
+		t.centers[ci] = c.Center
+		order[ci] = ci
+	}
+	t.build(order)
+	return t
+}
+
+// build adds the subtree of the given centers and returns the index of its
+// root node, or -1 for an empty subtree
+func (t *centroidIndex) build(order []int) int {
+	if len(order) == 0 {
+		return -1
+	}
+
+	dim, spread := 0, -1.0
+	for j := range t.centers[order[0]] {
+		lo, hi := t.centers[order[0]][j], t.centers[order[0]][j]
+		for _, ci := range order[1:] {
+			if v := t.centers[ci][j]; v < lo {
+				lo = v
+			} else if v > hi {
+				hi = v
+			}
+		}
+		if hi-lo > spread {
+			dim, spread = j, hi-lo
+		}
+	}
+	sort.Slice(order, func(a, b int) bool {
+		va, vb := t.centers[order[a]][dim], t.centers[order[b]][dim]
+		return va < vb || (va == vb && order[a] < order[b])
+	})
+
+	mid := len(order) / 2
+	n := len(t.nodes)
+	t.nodes = append(t.nodes, kdNode{ci: order[mid], dim: dim})
+	left := t.build(order[:mid])
+	right := t.build(order[mid+1:])
+	t.nodes[n].left, t.nodes[n].right = left, right
+	return n
+}
+
+// nearest returns the index of the center nearest to the observation and
+// the distance to it, measured by the configured metric. Of equally near
+// centers, the one with the lowest index wins, like a scan of all centers
+// would pick it
+func (t *centroidIndex) nearest(m Kmeans, o clusters.Observation) (int, float64) {
+	ci, dist := -1, -1.0
+	t.search(m, o, o.Coordinates(), 0, &ci, &dist)
+	return ci, dist
+}
+
+// search descends into the subtree of node n, into the side of the
+// observation first, and into the other side only if the splitting plane
+// is no farther away than the nearest center found so far
+func (t *centroidIndex) search(m Kmeans, o clusters.Observation, oc clusters.Coordinates, n int, ci *int, dist *float64) {
+	if n < 0 {
+		return
+	}
+	node := t.nodes[n]
+	center := t.centers[node.ci]
+	if d := m.distance(o, center); *dist < 0 || d < *dist || (d == *dist && node.ci < *ci) {
+		*ci, *dist = node.ci, d
+	}
+
+	diff := oc[node.dim] - center[node.dim]
+	near, far := node.left, node.right
+	if diff > 0 {
+		near, far = far, near
+	}
+	t.search(m, o, oc, near, ci, dist)
+
+	plane := diff * diff
+	if t.weights != nil {
+		plane *= t.weights[node.dim]
+	}
+	if plane <= *dist {
+		t.search(m, o, oc, far, ci, dist)
+	}
+}
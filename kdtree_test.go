@@ -0,0 +1,74 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestCentroidIndex(t *testing.T) {
+	// data points on a grid, equally near to many centers
+	var grid clusters.Observations
+	for i := 0; i < 400; i++ {
+		grid = append(grid, clusters.Coordinates{float64(i % 20), float64(i / 20)})
+	}
+	cc := clusters.Clusters{}
+	for i := 0; i < 100; i += 3 {
+		cc = append(cc, clusters.Cluster{Center: clusters.Coordinates{float64(i%10) * 2, float64(i/10) * 2}})
+	}
+	for _, weights := range [][]float64{nil, {0.5, 3}} {
+		km := New()
+		km.FeatureWeights = weights
+		indexed := km
+		indexed.index = newCentroidIndex(cc, weights)
+		for _, o := range grid {
+			ci, d := km.nearest(cc, o)
+			if ici, id := indexed.nearest(cc, o); ici != ci || id != d {
+				t.Errorf("Expected center %d at %f for %v, got %d at %f", ci, d, o, ici, id)
+			}
+		}
+	}
+
+	d, _ := MakeBlobs(4096, 64, 2, 0.5, rand.New(rand.NewSource(randomSeed)))
+	partition := func(index bool) (clusters.Clusters, Stats) {
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Init = InitKMeansPlusPlus
+		km.UseCentroidIndex = index
+		km.CountDistances = true
+		cc, stats, err := km.PartitionWithStats(d, 64)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc, stats
+	}
+	exp, scan := partition(false)
+	cc, indexed := partition(true)
+	if !reflect.DeepEqual(cc, exp) {
+		t.Errorf("Expected identical clusters with the centroid index")
+	}
+	if indexed.DistanceEvaluations >= scan.DistanceEvaluations {
+		t.Errorf("Expected fewer distance evaluations than %d with the centroid index, got %d", scan.DistanceEvaluations, indexed.DistanceEvaluations)
+	}
+}
+
+func benchmarkStepIndex(index bool, b *testing.B) {
+	d, _ := MakeBlobs(1<<14, 1024, 2, 1, rand.New(rand.NewSource(randomSeed)))
+	cc, err := Kmeans{Init: InitForgy}.seed(1024, d, rand.New(rand.NewSource(randomSeed)))
+	if err != nil {
+		b.Fatalf("Unexpected error seeding: %v", err)
+	}
+	assignment := make([]int, len(d))
+
+	km := New()
+	km.UseCentroidIndex = index
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		km.step(cc, d, assignment, nil, nil, 0, nil)
+	}
+}
+
+func BenchmarkStepScan1024Centroids(b *testing.B)  { benchmarkStepIndex(false, b) }
+func BenchmarkStepIndex1024Centroids(b *testing.B) { benchmarkStepIndex(true, b) }
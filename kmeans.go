@@ -1,21 +1,63 @@
 // Package kmeans implements the k-means clustering algorithm
+//
+// The parallel loops split their work into chunks of fixed size rather than
+// one per thread, and merge the partial results of the chunks in order, so
+// their results don't depend on the number of threads
 // See: https://en.wikipedia.org/wiki/K-means_clustering
 package kmeans
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"sync/atomic"
 	"sync"
+	"time"
 
 	"github.com/k----n/clusters"
-	"github.com/k----n/classifier/parallel"
 )
 
-// Kmeans configuration/option struct
+// ErrNoProgress is returned by Partition if FailOnNoProgress is set and the
+// first iteration didn't move any of the initial cluster centers
+var ErrNoProgress = errors.New("the first iteration didn't move any cluster center")
+
+// ErrKTooLarge is returned by Partition if AbortIfOverK is set and some of
+// the clusters kept ending up empty or with a single member
+type ErrKTooLarge struct {
+	// K is the requested number of clusters
+	K int
+	// NonEmpty is the number of clusters with more than one member in the
+	// last iteration, a hint at a better k
+	NonEmpty int
+}
+
+func (e ErrKTooLarge) Error() string {
+	return fmt.Sprintf("k=%d is too large, only %d clusters hold more than one data point", e.K, e.NonEmpty)
+}
+
+// Kmeans configuration/option struct. A configured Kmeans may be used from
+// multiple goroutines at once: all state of a call is local to it, and the
+// option slices are only read. The configured plotter however gets called
+// from every concurrent call, so it must be safe for concurrent use itself,
+// and so must the CentroidOutput
 type Kmeans struct {
 	// number of threads
 	Threads int
+	// Pool optionally runs the parallel loops, from seeding over the
+	// assignment and recenter phases to the inertia and the quality
+	// criteria, on a reusable WorkerPool, instead of on goroutines spawned
+	// per loop. Without it, every Partition call maintains a pool of
+	// Threads workers for its duration
+	Pool *WorkerPool
+	// Rand is the source of randomness used for seeding the clusters and
+	// refilling empty ones. Set it to a seeded source for reproducible
+	// single-threaded results. Every call draws the seed of its own source
+	// from it while holding a lock, so concurrent calls may share it, as
+	// long as it isn't used elsewhere at the same time. When nil, a fresh
+	// source is used per call
+	Rand *rand.Rand
 	// when a plotter is set, Plot gets called after each iteration
 	plotter Plotter
 	// deltaThreshold (in percent between 0.0 and 0.1) aborts processing if
@@ -24,6 +66,337 @@ type Kmeans struct {
 	// iterationThreshold aborts processing when the specified amount of
 	// algorithm iterations was reached
 	iterationThreshold int
+
+	// Init chooses the initial cluster centers, either one of the built-in
+	// InitMethods or a custom Initializer (defaults to InitRandom). The
+	// CandidatePool only restricts the built-in init methods
+	Init Initializer
+	// InitRetries makes a built-in init method seed again, up to the given
+	// number of times, whenever it places two centers on the same spot (a
+	// CentroidSeparation of at most 1e-18), which leaves one
+	// of them empty right away. If the centers still coincide after the
+	// retries, they get seeded by InitKMeansPlusPlus instead, which spaces
+	// them out. Zero disables the check
+	InitRetries int
+	// CandidatePool optionally restricts the data points which can be
+	// picked as initial centers (by InitForgy and InitKMeansPlusPlus) to
+	// the given indices into the dataset. When nil, all data points are
+	// candidates
+	CandidatePool []int
+	// InitLabels optionally seeds the initial centroids from partial labels:
+	// InitLabels[i] is the cluster of dataset[i] (between 0 and k-1), or -1
+	// if the data point is unlabeled. Each labeled group starts at the mean
+	// of its members, all other clusters are seeded as usual
+	InitLabels []int
+	// Weights optionally assigns a non-negative weight to each data point.
+	// Cluster centers become weighted means and the inertia weighs each
+	// data point's squared distance by its weight
+	Weights []float64
+	// FeatureWeights optionally scales the contribution of each dimension
+	// to the squared distance: the squared difference in dimension i gets
+	// multiplied by FeatureWeights[i]. The weights apply to fitting, the
+	// inertia and predictions alike, replacing the observations' own
+	// Distance by a weighted squared Euclidean distance. They can't be
+	// combined with a custom Distance
+	FeatureWeights []float64
+	// Distance optionally replaces the observations' own Distance as the
+	// metric for fitting, the inertia and predictions, e.g. by Gower. The
+	// cluster centers remain the means of their members, unless Center
+	// says otherwise
+	Distance DistanceFunc
+	// Spherical runs spherical k-means: data points get assigned by their
+	// cosine distance (1 minus the cosine similarity) to the centers, and
+	// all centers get normalized to unit length, so they can be used as
+	// direction vectors. Normalize the data points beforehand for them to
+	// weigh equally in the centers, and for SnapToData to keep the centers
+	// at unit length. It can't be combined with a custom Distance or
+	// FeatureWeights
+	Spherical bool
+	// ShuffleEachIteration processes the data points in a random order in
+	// each iteration, drawn from the source of randomness. The nearest
+	// cluster of a data point only depends on the centers, so the order
+	// doesn't change the assignment, but it's the order in which the data
+	// points get appended to the member lists of their clusters, which the
+	// centers get summed up in. Single-threaded, the order is reproducible
+	// for a seeded source of randomness
+	ShuffleEachIteration bool
+	// Center selects how the cluster centers are computed from their
+	// members (defaults to the mean)
+	Center CenterMethod
+	// CategoricalFeatures lists the dimensions holding category codes, of
+	// which CenterPrototype takes the mode instead of the mean
+	CategoricalFeatures []int
+	// Aggregator optionally replaces the built-in computation of the
+	// cluster centers from their members, e.g. by a median. It can't be
+	// combined with a Center other than CenterMean, and doesn't take the
+	// Weights into account
+	Aggregator Aggregator
+	// FusedRecenter sums up the members of each cluster while assigning
+	// the data points, so the mean centers are ready without another pass
+	// over the data. The members get summed up in the same order as by the
+	// separate pass, so the centers are identical. It has no effect with an
+	// Aggregator
+	FusedRecenter bool
+	// LeanMemory keeps no member lists while fitting, to cut the peak
+	// memory of large datasets: the centers get computed from the sums
+	// and counts of the members accumulated like with FusedRecenter, which
+	// it implies, so they're identical to the ones of a default fit. The
+	// member lists get filled in the order of the dataset once a run
+	// finished, so only the Plotter and the Checkpoint see clusters
+	// without members. It has no effect with an Aggregator, a
+	// BalancePenalty or CenterPrototype, which need the member lists
+	LeanMemory bool
+	// MortonOrder assigns the data points in the order of a space-filling
+	// curve (Z-order) through the bounding box of the dataset, computed
+	// once per run, so data points processed one after another tend to be
+	// close to each other and to the same centers, which improves the cache
+	// locality on large, low-dimensional data. The members still get
+	// appended to their clusters in the regular order, so the results don't
+	// change. It has no effect with FusedRecenter
+	MortonOrder bool
+	// PadDimensions makes Partition and its variants pad observations with
+	// fewer dimensions than the longest one with zeros, instead of relying
+	// on all observations having the same dimensions. A zero is a value
+	// like any other, so a padded observation is far from observations with
+	// large values in the missing dimensions, as if it had been measured as
+	// zero there. Padded observations get copied, so the members of the
+	// returned clusters are the padded copies, which carry no payload
+	PadDimensions bool
+	// CentroidConstraint optionally gets applied to each center after it
+	// got recomputed from its members (and normalized, if Spherical), to
+	// keep the centers in a valid region, e.g. by BoundingBox. It modifies
+	// the coordinates in place. Constrained centers aren't the means of their
+	// members anymore, so the iterations may converge slower or not at all,
+	// and reach the iteration threshold instead
+	CentroidConstraint func(c clusters.Coordinates)
+	// UseCentroidIndex builds a k-d tree over the centers in each
+	// iteration, and looks up the nearest center of each data point in it
+	// instead of measuring the distance to all k centers. The assignment
+	// stays exact, but it only pays off for large k in few dimensions: in
+	// many dimensions, the lookups end up visiting most centers anyway. It
+	// requires the observations' Distance to be the squared Euclidean
+	// distance, like the one of clusters.Coordinates, and has no effect
+	// with a custom Distance or in Spherical mode
+	UseCentroidIndex bool
+	// BalancePenalty softly balances the sizes of the clusters: while
+	// assigning the data points, every data point already assigned to a
+	// cluster in the iteration adds the penalty to the cost of assigning
+	// another one to it, on top of the distance to its center. Zero is the
+	// standard k-means, higher values trade inertia for balance. It makes
+	// the assignment depend on the order of the data points, so they get
+	// assigned sequentially, by index or in the order of
+	// ShuffleEachIteration, which keeps the results reproducible for a
+	// seeded source of randomness, but forgoes the threads for the
+	// assignment. It takes precedence over FusedRecenter, MortonOrder and
+	// UseCentroidIndex
+	BalancePenalty float64
+	// Refill selects the cluster donating a data point whenever a cluster
+	// ends up empty (defaults to RefillRandom)
+	Refill RefillMethod
+	// MaxReseedsPerIteration optionally limits how many empty clusters get
+	// refilled in a single iteration (or call of Step and Recenter), in the
+	// order of their indices. After a bad seeding, refilling many clusters
+	// at once shifts many data points and keeps the run from converging;
+	// spreading the recovery across iterations smooths it, at the price of
+	// leaving some clusters empty for an iteration or more. Zero refills all
+	// empty clusters
+	MaxReseedsPerIteration int
+
+	// NInit is the number of runs with different seeds, of which the one
+	// with the lowest inertia is returned (defaults to a single run)
+	NInit int
+	// TieTolerance is the relative difference of the inertias below which
+	// two runs count as equally good, so the earlier one is kept, rather
+	// than whichever happens to be lower in the last bits, e.g. from the
+	// order of summation. The runs draw their seeds from the source of
+	// randomness in order, so the same input and seed always yield the
+	// same run (defaults to DefaultTieTolerance; negative compares the
+	// inertias exactly)
+	TieTolerance float64
+	// RestartOnThrash aborts a run and restarts it from a fresh seed when
+	// the amount of data points shifting clusters stopped decreasing for
+	// ThrashWindow iterations. The result with the lowest inertia seen is
+	// returned
+	RestartOnThrash bool
+	// ThrashWindow is the number of iterations without a new low in shifted
+	// data points after which a run is considered thrashing (defaults to 10)
+	ThrashWindow int
+	// StableWindow is the number of consecutive iterations in which fewer
+	// data points than the delta threshold must shift clusters before a run
+	// is considered converged, so a brief dip in shifted data points doesn't
+	// stop it prematurely (defaults to 1, a single iteration)
+	StableWindow int
+	// AbortIfOverK makes Partition fail with ErrKTooLarge if, for
+	// ThrashWindow consecutive iterations, any of the clusters is left with
+	// at most a single member after refilling empty ones, which suggests k
+	// is too large for the data. Frozen clusters are exempt. A legitimate
+	// singleton cluster, e.g. of an outlier, triggers it too, unless the
+	// run converges within the window
+	AbortIfOverK bool
+	// MaxRestarts bounds the number of restarts caused by RestartOnThrash
+	// (defaults to 3)
+	MaxRestarts int
+	// Polish keeps iterating a run after it stopped, until not a single
+	// data point shifts clusters anymore, so the result is a true fixed
+	// point instead of one the delta threshold, the iteration threshold or
+	// the TargetInertia settled for. This may take many iterations beyond
+	// MaxIterations, bounded by the PolishMaxIterations, the MaxDuration
+	// and the MaxDistanceEvals. Thrashing runs, which get restarted, aren't
+	// polished. With a BalancePenalty or a CentroidConstraint, whose
+	// assignments and centers may keep cycling, Polish has no effect
+	Polish bool
+	// PolishMaxIterations bounds the number of iterations Polish adds to
+	// a run, in case refilled clusters keep it from reaching a fixed point
+	// (defaults to DefaultPolishFactor times the iteration threshold, or
+	// just DefaultPolishFactor for a zero threshold, which stops a run
+	// after its first iteration)
+	PolishMaxIterations int
+	// TargetInertia stops a run as soon as its inertia is at or below the
+	// target, whichever of the stop criteria triggers first. An unreachable
+	// target falls through to the delta and iteration thresholds. Zero
+	// disables the target
+	TargetInertia float64
+	// SnapToData replaces each final cluster center with the data point
+	// nearest to it (among the CandidatePool, if set) and reassigns the data
+	// points, so all centers are actual observations. This may slightly
+	// increase the inertia, and clusters whose centers snap to the same
+	// data point end up empty
+	SnapToData bool
+	// MaxDuration is the wall-clock budget of a Partition call across all
+	// runs and restarts. Once it's spent no further runs are started, and
+	// the best completed run is returned. If no run completed in time, the
+	// state of the interrupted one is returned. Zero disables the budget
+	MaxDuration time.Duration
+	// MaxDistanceEvals is the compute budget of a Partition call across all
+	// runs and restarts, in distance evaluations (as counted for
+	// CountDistances), which unlike the MaxDuration doesn't depend on the
+	// hardware. It's checked after each iteration, so the budget may be
+	// exceeded by the evaluations of an iteration and a seeding. Once
+	// it's spent, the call stops like for the MaxDuration, whichever of the
+	// stop criteria triggers first, so the result may not have converged.
+	// Zero disables the budget
+	MaxDistanceEvals int
+	// FrozenCentroids optionally lists clusters (indices into the initial
+	// centroids, between 0 and k-1) whose centers must not move. They take
+	// part in the assignment of the data points, but keep their initial
+	// center, even when empty or when snapping to data points
+	FrozenCentroids []int
+	// FailOnNoProgress makes Partition fail with ErrNoProgress if the first
+	// iteration neither refills an empty cluster nor moves any of the
+	// initial centers, which tells a run that converged instantly (e.g.
+	// because warm-start centroids were already optimal) apart from a
+	// regular one. By default such a run succeeds
+	FailOnNoProgress bool
+	// TrackChanges makes PartitionWithStats report the last iteration at
+	// which each data point shifted clusters. It's opt-in to keep the
+	// bookkeeping off the default path
+	TrackChanges bool
+	// TrackTimings makes PartitionWithStats report how long the phases of
+	// each iteration took. It's opt-in to keep the clock off the default
+	// path
+	TrackTimings bool
+	// RecordHistory makes PartitionWithStats record the centers after each
+	// iteration, so the run can be replayed afterwards, e.g. rendered as
+	// an animation, without a live Plotter. It's opt-in, as the history
+	// takes memory in the order of iterations × k × d
+	RecordHistory bool
+	// CountDistances makes PartitionWithStats count the distance
+	// evaluations of the call. It's opt-in to keep the atomic counter off
+	// the default path
+	CountDistances bool
+	// Checkpoint optionally gets called with the clusters and the cluster
+	// index of each data point every CheckpointEvery iterations of each
+	// run, e.g. to persist the progress of long runs. It runs synchronously
+	// between iterations, so it sees a consistent state, which it must not
+	// modify or retain. If it returns an error, the call aborts with it
+	Checkpoint func(iteration int, cc clusters.Clusters, assignment []int) error
+	// CheckpointEvery is the number of iterations between checkpoints
+	// (defaults to 1, every iteration)
+	CheckpointEvery int
+	// SkipEmptyClusters makes Predict and its variants skip the clusters
+	// without members, whose centers may be stale, e.g. after k was chosen
+	// too large, so no observation gets assigned to them. If none of the
+	// clusters has members, like the ones of LoadModel, all of them are
+	// considered. It doesn't affect fitting
+	SkipEmptyClusters bool
+	// CentroidOutput optionally receives the final centroids of Partition
+	// and PartitionInto as JSON, in the format of SaveModel
+	CentroidOutput io.Writer
+	// Warn optionally receives advisory messages about suspicious inputs
+	// of Partition and its variants, e.g. features with zero variance,
+	// duplicate observations or dimensions of vastly different scales,
+	// which don't keep the call from proceeding. When nil, the checks don't
+	// run at all
+	Warn func(string)
+	// CSVDelimiter is the field delimiter of WriteAssignmentsCSV and
+	// WriteCentroidsCSV (defaults to a comma)
+	CSVDelimiter rune
+
+	// counter of the distance evaluations of a call, if CountDistances
+	// is set
+	distances *atomic.Uint64
+	// processing order of the data points of a run, if MortonOrder is set
+	morton []int
+	// receiver of a snapshot after each iteration, during PartitionStream
+	snapshots snapshotStream
+	// clock of the phases of the current iteration of a run, if
+	// TrackTimings is set
+	clock *phaseClock
+	// k-d tree over the centers of the clusters being assigned in a step,
+	// if UseCentroidIndex is set
+	index *centroidIndex
+}
+
+// Stats reports how a call of PartitionWithStats went
+type Stats struct {
+	// DistanceEvaluations is the number of distances computed between data
+	// points and centers (or other data points, while seeding) across all
+	// runs. It is only counted if CountDistances is set
+	DistanceEvaluations uint64
+	// LastChanged holds, for each data point of the dataset, the last
+	// iteration (counting from 0) of the returned run at which it shifted
+	// clusters. Every data point gets assigned in iteration 0, so a data
+	// point which never shifted afterwards is marked 0. Reassignments
+	// after the iterations, by SnapToData, are not tracked. It is only
+	// populated if TrackChanges is set
+	LastChanged []int
+	// Timings holds the durations of the phases of each iteration of the
+	// returned run. It is only populated if TrackTimings is set
+	Timings []IterationTimings
+	// History holds the centers of the returned run: its seeds, followed
+	// by the centers after each iteration, so History[i][ci] is the center
+	// of cluster ci after iteration i-1. Adjustments after the iterations,
+	// by SnapToData, are not recorded. It is only populated if
+	// RecordHistory is set
+	History [][]clusters.Coordinates
+}
+
+// result is the outcome of a single run of the algorithm
+type result struct {
+	clusters clusters.Clusters
+	// cluster index of each data point
+	assignment []int
+	// (weighted) sum of squared distances of the data points to their
+	// cluster center, NaN unless it was needed to compare runs
+	inertia float64
+	// whether the run got aborted because it was thrashing
+	thrashed bool
+	// whether the run got interrupted because the time budget was spent
+	interrupted bool
+	// last iteration at which each data point shifted clusters, if
+	// TrackChanges is set
+	lastChanged []int
+	// durations of the phases of each iteration, if TrackTimings is set
+	timings []IterationTimings
+	// centers of the seeds and after each iteration, if RecordHistory is
+	// set
+	history [][]clusters.Coordinates
+	// number of iterations run
+	iterations int
+	// whether the run stopped because too few data points shifted clusters
+	converged bool
+	// number of times a cluster ended up empty and got refilled
+	refills int
 }
 
 // The Plotter interface lets you implement your own plotters
@@ -31,6 +404,27 @@ type Plotter interface {
 	Plot(cc clusters.Clusters, iteration int) error
 }
 
+// The MovementPlotter interface lets you implement plotters which animate the
+// movement of the cluster centers. If the configured plotter implements it,
+// PlotWithMovement gets called with the centers of the previous iteration
+// instead of Plot
+type MovementPlotter interface {
+	Plotter
+	PlotWithMovement(cc clusters.Clusters, prev []clusters.Coordinates, iteration int) error
+}
+
+// DefaultMaxIterations is the iteration threshold of New and NewWithOptions:
+// the maximum number of iterations of a run
+const DefaultMaxIterations = 96
+
+// DefaultPolishFactor is the default PolishMaxIterations as a multiple of
+// the iteration threshold
+const DefaultPolishFactor = 4
+
+// DefaultTieTolerance is the default TieTolerance, the relative difference
+// of the inertias below which two runs count as equally good
+const DefaultTieTolerance = 1e-9
+
 // NewWithOptions returns a Kmeans configuration struct with custom settings
 func NewWithOptions(deltaThreshold float64, plotter Plotter) (Kmeans, error) {
 	if deltaThreshold <= 0.0 || deltaThreshold >= 1.0 {
@@ -40,7 +434,7 @@ func NewWithOptions(deltaThreshold float64, plotter Plotter) (Kmeans, error) {
 	return Kmeans{
 		plotter:            plotter,
 		deltaThreshold:     deltaThreshold,
-		iterationThreshold: 96,
+		iterationThreshold: DefaultMaxIterations,
 	}, nil
 }
 
@@ -50,90 +444,1058 @@ func New() Kmeans {
 	return m
 }
 
+// MaxIterations returns the iteration threshold of the configuration, the
+// maximum number of iterations of a run. It's DefaultMaxIterations unless
+// changed, or 0 for a Kmeans which wasn't created by New or
+// NewWithOptions, whose runs stop after their first iteration
+func (m Kmeans) MaxIterations() int {
+	return m.iterationThreshold
+}
+
 // Partition executes the k-means algorithm on the given dataset and
-// partitions it into k clusters
+// partitions it into k clusters. The members of the returned clusters are the
+// observations of the dataset as passed in, so any payload they carry (see
+// Record) can be mapped back to its cluster
 func (m Kmeans) Partition(dataset clusters.Observations, k int) (clusters.Clusters, error) {
-	if k > len(dataset) {
-		return clusters.Clusters{}, fmt.Errorf("the size of the data set must at least equal k")
+	res, err := m.partition(dataset, k)
+	if err != nil {
+		return nil, err
 	}
+	if err := m.writeCentroids(res.clusters); err != nil {
+		return nil, err
+	}
+	return res.clusters, nil
+}
 
-	cc, err := clusters.New(k, dataset)
+// PartitionWithStats executes the k-means algorithm like Partition, and
+// additionally reports statistics about the call
+func (m Kmeans) PartitionWithStats(dataset clusters.Observations, k int) (clusters.Clusters, Stats, error) {
+	if m.CountDistances {
+		m.distances = new(atomic.Uint64)
+	}
+
+	res, err := m.partition(dataset, k)
 	if err != nil {
-		return cc, err
+		return nil, Stats{}, err
+	}
+	if err := m.writeCentroids(res.clusters); err != nil {
+		return nil, Stats{}, err
 	}
 
-	points := make([]int, len(dataset))
-	var changes atomic.Uint64
-	changes.Add(1)
+	stats := Stats{
+		LastChanged: res.lastChanged,
+		Timings:     res.timings,
+		History:     res.history,
+	}
+	if m.distances != nil {
+		stats.DistanceEvaluations = m.distances.Load()
+	}
+	return res.clusters, stats, nil
+}
+
+// Result is the outcome of Fit
+type Result struct {
+	// Clusters holds the clusters, whose members are the observations of
+	// the dataset as passed in
+	Clusters clusters.Clusters
+	// Assignment holds the cluster index of each data point
+	Assignment []int
+	// Inertia is the (weighted) sum of squared distances of the data points
+	// to their cluster center
+	Inertia float64
+	// Iterations is the number of iterations of the returned run
+	Iterations int
+	// Converged reports whether the returned run stopped because too few
+	// data points shifted clusters, rather than because it hit the
+	// iteration threshold, the TargetInertia, the MaxDuration or the
+	// MaxDistanceEvals
+	Converged bool
+	// EmptyClusters is the number of times a cluster of the returned run
+	// ended up empty and got refilled
+	EmptyClusters int
+	// Stats holds the statistics PartitionWithStats reports
+	Stats Stats
+}
 
-	for i := 0; changes.Load() > 0; i++ {
-		changes.Store(0)
-		cc.ResetThreads(m.Threads)
-		var mut [256]sync.RWMutex
+// Fit executes the k-means algorithm like Partition, and reports everything
+// known about the returned clustering. It is the recommended entry point
+func (m Kmeans) Fit(dataset clusters.Observations, k int) (Result, error) {
+	if m.CountDistances {
+		m.distances = new(atomic.Uint64)
+	}
 
-		parallel.ForEach(len(dataset), m.Threads, func (p int) {
-			point := dataset[p]
-			for i := range mut {
-				mut[i].RLock()
-			}
-			ci := cc.Nearest(point)
-			for i := range mut {
-				mut[i].RUnlock()
+	res, err := m.partition(dataset, k)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := m.writeCentroids(res.clusters); err != nil {
+		return Result{}, err
+	}
+
+	stats := Stats{
+		LastChanged: res.lastChanged,
+		Timings:     res.timings,
+		History:     res.history,
+	}
+	if m.distances != nil {
+		// the evaluations of the call, not of the report
+		stats.DistanceEvaluations = m.distances.Load()
+	}
+	if math.IsNaN(res.inertia) {
+		res.inertia = m.inertia(dataset, res.assignment, res.clusters)
+	}
+	return Result{
+		Clusters:      res.clusters,
+		Assignment:    res.assignment,
+		Inertia:       res.inertia,
+		Iterations:    res.iterations,
+		Converged:     res.converged,
+		EmptyClusters: res.refills,
+		Stats:         stats,
+	}, nil
+}
+
+// ByID returns the cluster index of each data point keyed by its ID, like
+// PredictByID: ids[i] is the ID of the i-th data point of the dataset
+func (r Result) ByID(ids []interface{}) (map[interface{}]int, error) {
+	return assignmentByID(r.Assignment, ids)
+}
+
+// Refit executes the k-means algorithm like Partition on a new dataset,
+// e.g. a grown version of the previous one, starting from the centers of
+// the previous clusters rather than seeding anew. k is the number of
+// previous clusters, and the clusters keep their indices, so they can be
+// tracked across versions of the dataset. As all runs would start from the
+// same centers, a single run is executed, without restarts
+func (m Kmeans) Refit(prev clusters.Clusters, dataset clusters.Observations) (clusters.Clusters, error) {
+	if len(prev) == 0 {
+		return nil, fmt.Errorf("there must be at least one previous cluster")
+	}
+
+	centers := make(WarmStart, len(prev))
+	for ci, c := range prev {
+		centers[ci] = c.Center
+	}
+	m.Init = centers
+	m.InitLabels = nil
+	m.NInit = 1
+	m.RestartOnThrash = false
+	return m.Partition(dataset, len(prev))
+}
+
+// PartitionInto executes the k-means algorithm like Partition, but stores
+// the clusters in dst and the cluster index of each data point in
+// assignment, reusing their storage across calls to save allocations on hot
+// paths. dst must not be nil; it gets grown to k clusters if it holds fewer,
+// and so do the centers and member lists of its clusters. assignment may be
+// nil if the caller doesn't need it, otherwise it must hold at least
+// len(dataset) entries, since a slice passed by value can't be grown for
+// the caller. The previous contents of both buffers get overwritten
+func (m Kmeans) PartitionInto(dst *clusters.Clusters, assignment []int, dataset clusters.Observations, k int) error {
+	if assignment != nil && len(assignment) < len(dataset) {
+		return fmt.Errorf("the size of the assignment must at least equal the size of the data set")
+	}
+
+	res, err := m.partitionInto(dataset, k, result{clusters: *dst, assignment: assignment})
+	if err != nil {
+		return err
+	}
+
+	// a later run may have won, whose storage isn't the caller's
+	*dst = copyClusters(*dst, res.clusters)
+	copy(assignment, res.assignment)
+	return m.writeCentroids(*dst)
+}
+
+// PartitionReusing executes the k-means algorithm like Partition, reusing
+// the storage of previously returned clusters cc for the returned ones, like
+// PartitionInto does, to save allocations when clustering repeatedly with
+// the same k and dimensionality. The contents of cc get overwritten, so cc
+// must not be used afterwards; the results match the ones of Partition. cc
+// may be nil
+func (m Kmeans) PartitionReusing(cc clusters.Clusters, dataset clusters.Observations, k int) (clusters.Clusters, error) {
+	if err := m.PartitionInto(&cc, nil, dataset, k); err != nil {
+		return nil, err
+	}
+	return cc, nil
+}
+
+// writeCentroids writes the centroids to the CentroidOutput, if set
+func (m Kmeans) writeCentroids(cc clusters.Clusters) error {
+	if m.CentroidOutput == nil {
+		return nil
+	}
+	if err := SaveModel(m.CentroidOutput, cc); err != nil {
+		return fmt.Errorf("failed to write centroids: %s", err)
+	}
+	return nil
+}
+
+// partition executes all configured runs of the k-means algorithm and
+// returns the best one
+func (m Kmeans) partition(dataset clusters.Observations, k int) (result, error) {
+	return m.partitionInto(dataset, k, result{})
+}
+
+// validate checks the dataset and the configuration for partitioning the
+// dataset into k clusters, or up to kMax clusters discovered starting from
+// k, and returns the dataset to partition, padded if PadDimensions is set
+func (m Kmeans) validate(dataset clusters.Observations, k, kMax int) (clusters.Observations, error) {
+	if m.PadDimensions {
+		dataset = padDimensions(dataset)
+	}
+	if k > len(dataset) {
+		return nil, fmt.Errorf("the size of the data set must at least equal k")
+	}
+	// with fewer distinct data points than clusters, refilling the surplus
+	// empty clusters would never settle
+	if !distinctAtLeast(dataset, k) {
+		return nil, fmt.Errorf("the number of distinct data points must at least equal k")
+	}
+
+	if m.Weights != nil {
+		if len(m.Weights) != len(dataset) {
+			return nil, fmt.Errorf("the number of weights must equal the size of the data set")
+		}
+		for i, w := range m.Weights {
+			if w < 0 || math.IsNaN(w) {
+				return nil, fmt.Errorf("weight %f of data point %d must not be negative", w, i)
 			}
-			mut[ci & 255].Lock()
-			cc[ci].Append(point)
-			if points[p] != ci {
-				points[p] = ci
-				changes.Add(1)
+		}
+	}
+
+	if m.BalancePenalty < 0 || math.IsNaN(m.BalancePenalty) {
+		return nil, fmt.Errorf("the balance penalty %f must not be negative", m.BalancePenalty)
+	}
+
+	if err := m.checkMetric(dataset); err != nil {
+		return nil, err
+	}
+	if err := m.checkCenter(dataset); err != nil {
+		return nil, err
+	}
+	m.warn(dataset)
+
+	for _, ci := range m.FrozenCentroids {
+		if ci < 0 || ci >= kMax {
+			return nil, fmt.Errorf("frozen centroid %d is out of bounds (must be between 0 and %d)", ci, kMax-1)
+		}
+	}
+
+	if m.CandidatePool != nil {
+		if len(m.CandidatePool) < k {
+			return nil, fmt.Errorf("the candidate pool must contain at least k data points")
+		}
+		for _, p := range m.CandidatePool {
+			if p < 0 || p >= len(dataset) {
+				return nil, fmt.Errorf("candidate %d is out of bounds (must be between 0 and %d)", p, len(dataset)-1)
 			}
-			mut[ci & 255].Unlock()
-		})
+		}
+	}
 
-		parallel.ForEach(len(cc), m.Threads, func (ci int) {
-			if len(cc[ci].Observations) == 0 {
-				// During the iterations, if any of the cluster centers has no
-				// data points associated with it, assign a random data point
-				// to it.
-				// Also see: http://user.ceng.metu.edu.tr/~tcan/ceng465_f1314/Schedule/KMeansEmpty.html
-				var ri int
-				for {
-					// find a cluster with at least two data points, otherwise
-					// we're just emptying one cluster to fill another
-					ri = rand.Intn(len(dataset)) //nolint:gosec // rand.Intn is good enough for this
-					mut[ri & 255].RLock()
-					if len(cc[points[ri]].Observations) > 1 {
-						mut[ri & 255].RUnlock()
-						break
-					} else {
-						mut[ri & 255].RUnlock()
-					}
-				}
-				mut[ci & 255].Lock()
-				cc[ci].Append(dataset[ri])
-				points[ri] = ci
-				mut[ci & 255].Unlock()
+	return dataset, nil
+}
+
+// partitionInto is partition reusing the storage of buf for the runs. The
+// storage of every run which isn't the best so far gets reused by the next
+// one
+func (m Kmeans) partitionInto(dataset clusters.Observations, k int, buf result) (result, error) {
+	dataset, err := m.validate(dataset, k, k)
+	if err != nil {
+		return result{}, err
+	}
+
+	if _, builtin := m.Init.(InitMethod); k > 0 && len(dataset) == k && (m.Init == nil || builtin) &&
+		m.InitLabels == nil && m.FrozenCentroids == nil {
+		return m.singletons(dataset), nil
+	}
+
+	var deadline time.Time
+	if m.MaxDuration > 0 {
+		deadline = time.Now().Add(m.MaxDuration)
+	}
+	if m.MaxDistanceEvals > 0 && m.distances == nil {
+		m.distances = new(atomic.Uint64)
+	}
+	if m.Pool == nil && m.Threads > 1 {
+		m.Pool = NewWorkerPool(m.Threads)
+		defer m.Pool.Close()
+	}
+
+	runs := m.NInit
+	if runs < 1 {
+		runs = 1
+	}
+	maxRestarts := m.MaxRestarts
+	if maxRestarts <= 0 {
+		maxRestarts = 3
+	}
+
+	rng := m.rand()
+
+	var best result
+	for r, restarts := 0, 0; r < runs; {
+		res, err := m.run(dataset, k, rng, m.RestartOnThrash && restarts < maxRestarts, deadline, buf)
+		if err != nil {
+			return result{}, err
+		}
+		if runs > 1 || m.RestartOnThrash {
+			res.inertia = m.inertia(dataset, res.assignment, res.clusters)
+		}
+		// completed runs always beat interrupted ones
+		if best.clusters == nil ||
+			(best.interrupted && !res.interrupted) ||
+			(best.interrupted == res.interrupted && m.beats(res.inertia, best.inertia)) {
+			best, buf = res, best
+		} else {
+			buf = res
+		}
+		if (!deadline.IsZero() && time.Now().After(deadline)) || m.budgetSpent() {
+			break
+		}
+
+		if res.thrashed {
+			restarts++
+			continue
+		}
+		r++
+	}
+
+	return best, nil
+}
+
+// polishes returns whether runs get polished, see Polish
+func (m Kmeans) polishes() bool {
+	return m.Polish && m.BalancePenalty == 0 && m.CentroidConstraint == nil
+}
+
+// polishMaxIterations returns the maximum number of iterations Polish adds
+// to a run
+func (m Kmeans) polishMaxIterations() int {
+	if m.PolishMaxIterations > 0 {
+		return m.PolishMaxIterations
+	}
+	if m.iterationThreshold > 0 {
+		return DefaultPolishFactor * m.iterationThreshold
+	}
+	return DefaultPolishFactor
+}
+
+// beats returns whether a run with the given inertia beats the best one so
+// far by more than the TieTolerance
+func (m Kmeans) beats(inertia, best float64) bool {
+	tol := m.TieTolerance
+	if tol == 0 {
+		tol = DefaultTieTolerance
+	}
+	if tol < 0 || math.IsInf(best, 0) {
+		return inertia < best
+	}
+	return inertia < best-tol*math.Abs(best)
+}
+
+// singletons returns the trivial partition of a dataset of exactly k
+// distinct data points, each one in a cluster of its own, in the order of
+// the dataset. It's the optimum any run would converge to, so it's returned
+// without iterating, as converged. Initializers and frozen centroids which
+// order the clusters themselves get iterated as usual
+func (m Kmeans) singletons(dataset clusters.Observations) result {
+	cc := make(clusters.Clusters, len(dataset))
+	assignment := make([]int, len(dataset))
+	for i, o := range dataset {
+		cc[i] = clusters.Cluster{
+			Center:       append(clusters.Coordinates{}, o.Coordinates()...),
+			Observations: clusters.Observations{o},
+		}
+		assignment[i] = i
+	}
+	m.recenter(cc, dataset, assignment)
 
-				// Ensure that we always see at least one more iteration after
-				// randomly assigning a data point to a cluster
-				changes.Add(uint64(len(dataset)))
+	var lastChanged []int
+	if m.TrackChanges {
+		lastChanged = make([]int, len(dataset))
+	}
+	return result{
+		clusters:    cc,
+		assignment:  assignment,
+		inertia:     math.NaN(),
+		lastChanged: lastChanged,
+		converged:   true,
+	}
+}
+
+// run executes a single run of the k-means algorithm from a fresh seed. If
+// restartable is set, the run gets aborted as soon as it's thrashing. Unless
+// the deadline is zero, the run gets interrupted once it passed. The run
+// reuses the storage of buf where possible
+func (m Kmeans) run(dataset clusters.Observations, k int, rng *rand.Rand, restartable bool, deadline time.Time, buf result) (result, error) {
+	cc, err := m.seedSeparated(k, dataset, rng)
+	if err != nil {
+		return result{}, err
+	}
+	if m.InitLabels != nil {
+		if err := m.seedFromLabels(cc, dataset); err != nil {
+			return result{}, err
+		}
+	}
+
+	if buf.clusters != nil {
+		cc = copyClusters(buf.clusters, cc)
+	}
+
+	return m.iterate(dataset, cc, buf.assignment, rng, restartable, deadline)
+}
+
+// iterate runs the k-means iterations on the dataset, starting from the
+// centers of the given clusters. The assignment of the data points gets
+// stored in points, which is grown if it's too small
+func (m Kmeans) iterate(dataset clusters.Observations, cc clusters.Clusters, points []int, rng *rand.Rand, restartable bool, deadline time.Time) (result, error) {
+	thrashWindow := m.ThrashWindow
+	if thrashWindow <= 0 {
+		thrashWindow = 10
+	}
+	// lowest amount of shifted data points seen so far, and how many
+	// iterations ago it was seen
+	minChanges, sinceMin := uint64(math.MaxUint64), 0
+	thrashed, interrupted := false, false
+	// number of consecutive iterations with degenerate clusters
+	degenerate := 0
+	iterations, refills := 0, 0
+	checkpointEvery := m.CheckpointEvery
+	if checkpointEvery < 1 {
+		checkpointEvery = 1
+	}
+	// number of consecutive iterations below the delta threshold
+	stable, stableWindow := 0, m.StableWindow
+	if stableWindow < 1 {
+		stableWindow = 1
+	}
+
+	if cap(points) < len(dataset) {
+		points = make([]int, len(dataset))
+	} else {
+		points = points[:len(dataset)]
+		for i := range points {
+			points[i] = 0
+		}
+	}
+	frozen := m.frozen(len(cc))
+	if m.MortonOrder && m.morton == nil {
+		m.morton = mortonOrder(dataset)
+	}
+	var lastChanged []int
+	if m.TrackChanges {
+		lastChanged = make([]int, len(dataset))
+	}
+	var timings []IterationTimings
+	if m.TrackTimings {
+		m.clock = &phaseClock{}
+	}
+	var history [][]clusters.Coordinates
+	if m.RecordHistory {
+		history = [][]clusters.Coordinates{copyCenters(cc)}
+	}
+	// the initial assignment counts as a change
+	changes := uint64(1)
+
+	for i := 0; changes > 0; i++ {
+		mp, _ := m.plotter.(MovementPlotter)
+		var prev []clusters.Coordinates
+		if mp != nil {
+			prev = make([]clusters.Coordinates, len(cc))
+			for ci := range cc {
+				prev[ci] = append(clusters.Coordinates{}, cc[ci].Center...)
 			}
-		})
+		}
+		var seeds []clusters.Coordinates
+		if m.FailOnNoProgress && i == 0 {
+			// copies, as the step may move centers in place
+			seeds = copyCenters(cc)
+		}
+
+		shifted, refilled := m.step(cc, dataset, points, rng, frozen, i, lastChanged)
+		if m.clock != nil {
+			timings = append(timings, m.clock.take())
+		}
+		if history != nil {
+			history = append(history, copyCenters(cc))
+		}
+		changes = shifted
+		iterations++
+		refills += len(refilled)
+		if len(refilled) > 0 {
+			// Ensure that we always see at least one more iteration after
+			// randomly assigning a data point to a cluster
+			changes += uint64(len(refilled) * len(dataset))
+		}
 
-		if changes.Load() > 0 {
-			cc.RecenterThreads(m.Threads)
+		if seeds != nil && len(refilled) == 0 && !centersMoved(cc, seeds) {
+			return result{}, ErrNoProgress
+		}
+		if m.AbortIfOverK {
+			var sizes []int
+			if m.lean() {
+				sizes = memberCounts(len(cc), points)
+			}
+			if n := nonDegenerate(cc, frozen, sizes); n < len(cc) {
+				degenerate++
+				if degenerate >= thrashWindow {
+					return result{}, ErrKTooLarge{K: len(cc), NonEmpty: n}
+				}
+			} else {
+				degenerate = 0
+			}
 		}
 		if m.plotter != nil {
-			err := m.plotter.Plot(cc, -int(changes.Load()))
+			var err error
+			if mp != nil {
+				err = mp.PlotWithMovement(cc, prev, -int(changes))
+			} else {
+				err = m.plotter.Plot(cc, -int(changes))
+			}
 			if err != nil {
-				return nil, fmt.Errorf("failed to plot chart: %s", err)
+				return result{}, fmt.Errorf("failed to plot chart: %s", err)
 			}
 		}
-		if i == m.iterationThreshold ||
-			int(changes.Load()) < int(float64(len(dataset))*m.deltaThreshold) {
+
+		if m.Checkpoint != nil && (i+1)%checkpointEvery == 0 {
+			if err := m.Checkpoint(i, cc, points); err != nil {
+				return result{}, err
+			}
+		}
+
+		if changes < minChanges {
+			minChanges, sinceMin = changes, 0
+		} else {
+			sinceMin++
+		}
+		if restartable && sinceMin >= thrashWindow {
+			thrashed = true
+			break
+		}
+		// only spend a pass over the data on the inertia if a stop
+		// criterion needs it
+		if m.iterationInertia() {
+			in := m.inertia(dataset, points, cc)
+			if m.snapshots != nil {
+				m.snapshots.emit(snapshotOf(cc, i, int(changes), in))
+			}
+			if m.TargetInertia > 0 && in <= m.TargetInertia {
+				break
+			}
+		}
+		if (!deadline.IsZero() && time.Now().After(deadline)) || m.budgetSpent() {
+			interrupted = true
+			break
+		}
+		if int(changes) < int(float64(len(dataset))*m.deltaThreshold) {
+			stable++
+		} else {
+			stable = 0
+		}
+		if i == m.iterationThreshold || stable >= stableWindow {
 			// fmt.Println("Aborting:", changes, int(float64(len(dataset))*m.TerminationThreshold))
 			break
 		}
 	}
 
+	if m.polishes() && !thrashed && !interrupted {
+		for n := 0; changes > 0 && n < m.polishMaxIterations(); n++ {
+			shifted, refilled := m.step(cc, dataset, points, rng, frozen, iterations, lastChanged)
+			if m.clock != nil {
+				timings = append(timings, m.clock.take())
+			}
+			if history != nil {
+				history = append(history, copyCenters(cc))
+			}
+			changes = shifted + uint64(len(refilled))
+			iterations++
+			refills += len(refilled)
+			if (!deadline.IsZero() && time.Now().After(deadline)) || m.budgetSpent() {
+				interrupted = true
+				break
+			}
+		}
+	}
+
+	if m.lean() {
+		fillMembers(cc, dataset, points)
+	}
+	if m.Spherical && !m.SnapToData {
+		// centers which never got recentered still hold their seeds
+		normalizeCenters(cc, frozen)
+	}
+	if m.SnapToData {
+		m.snapToData(cc, dataset)
+		m.reassign(cc, dataset, points)
+	}
+
+	return result{
+		clusters:    cc,
+		assignment:  points,
+		inertia:     math.NaN(),
+		thrashed:    thrashed,
+		interrupted: interrupted,
+		lastChanged: lastChanged,
+		timings:     timings,
+		history:     history,
+		iterations:  iterations,
+		converged:   !thrashed && !interrupted && (changes == 0 || stable >= stableWindow),
+		refills:     refills,
+	}, nil
+}
+
+// Step performs a single iteration of the k-means algorithm in place: it
+// assigns every data point of the dataset to its nearest cluster, updating
+// assignment and the members of the clusters, refills empty clusters, and
+// moves the centers of the clusters to their new members. assignment holds
+// the cluster index of each data point from the previous step (any valid
+// cluster index for the first one). It returns the number of data points
+// which changed clusters, including the ones moved to refilled clusters
+func (m Kmeans) Step(cc clusters.Clusters, dataset clusters.Observations, assignment []int) (changed int, err error) {
+	if len(cc) == 0 {
+		return 0, fmt.Errorf("k must be greater than 0")
+	}
+	if len(assignment) != len(dataset) {
+		return 0, fmt.Errorf("the size of the assignment must equal the size of the data set")
+	}
+	for i, ci := range assignment {
+		if ci < 0 || ci >= len(cc) {
+			return 0, fmt.Errorf("cluster %d of data point %d is out of bounds (must be between 0 and k-1)", ci, i)
+		}
+	}
+	if err := m.checkMetric(dataset); err != nil {
+		return 0, err
+	}
+	if err := m.checkCenter(dataset); err != nil {
+		return 0, err
+	}
+	if m.Weights != nil && len(m.Weights) != len(dataset) {
+		return 0, fmt.Errorf("the number of weights must equal the size of the data set")
+	}
+
+	shifted, refilled := m.step(cc, dataset, assignment, m.rand(), m.frozen(len(cc)), 0, nil)
+	if m.lean() {
+		fillMembers(cc, dataset, assignment)
+	}
+	return int(shifted) + len(refilled), nil
+}
+
+// step assigns every data point to its nearest cluster, refills empty
+// clusters and, if any data point shifted clusters, recenters the clusters.
+// It returns the number of data points which shifted clusters during the
+// assignment and the indices of the data points moved to refilled
+// clusters. Unless lastChanged is nil, the iteration gets recorded for
+// every shifted data point
+func (m Kmeans) step(cc clusters.Clusters, dataset clusters.Observations, points []int, rng *rand.Rand, frozen []bool, iteration int, lastChanged []int) (uint64, []int) {
+	m.clock.start()
+	if m.BalancePenalty > 0 {
+		return m.stepBalanced(cc, dataset, points, rng, frozen, iteration, lastChanged)
+	}
+	if m.UseCentroidIndex && m.Distance == nil && !m.Spherical {
+		m.index = newCentroidIndex(cc, m.FeatureWeights)
+	}
+	if (m.FusedRecenter || m.lean()) && m.Aggregator == nil {
+		return m.stepFused(cc, dataset, points, rng, frozen, iteration, lastChanged)
+	}
+	// keep the storage of the member lists for the next assignment
+	for ci := range cc {
+		cc[ci].Observations = cc[ci].Observations[:0]
+	}
+	var changes atomic.Uint64
+	var mut [256]sync.RWMutex
+	var order []int
+	if m.ShuffleEachIteration {
+		order = rng.Perm(len(dataset))
+	}
+
+	if m.MortonOrder {
+		return m.stepMorton(cc, dataset, points, rng, frozen, iteration, lastChanged, order)
+	}
+
+	m.forEach(len(dataset), func (p int) {
+		if order != nil {
+			p = order[p]
+		}
+		point := dataset[p]
+		for i := range mut {
+			mut[i].RLock()
+		}
+		ci, _ := m.nearest(cc, point)
+		for i := range mut {
+			mut[i].RUnlock()
+		}
+		mut[ci & 255].Lock()
+		cc[ci].Append(point)
+		if points[p] != ci {
+			points[p] = ci
+			changes.Add(1)
+			if lastChanged != nil {
+				lastChanged[p] = iteration
+			}
+		}
+		mut[ci & 255].Unlock()
+	})
+	m.clock.lap(phaseAssignment)
+
+	refilled := m.refillEmpty(cc, dataset, points, rng, frozen)
+	if lastChanged != nil {
+		for _, p := range refilled {
+			lastChanged[p] = iteration
+		}
+	}
+	m.clock.lap(phaseReseed)
+
+	if changes.Load() > 0 || len(refilled) > 0 {
+		m.recenter(cc, dataset, points)
+	}
+	m.clock.lap(phaseRecenter)
+	return changes.Load(), refilled
+}
+
+// nonDegenerate returns the number of clusters with more than one member,
+// counting frozen clusters regardless of their members. The sizes of the
+// clusters are given by sizes, unless nil, otherwise by their member lists
+func nonDegenerate(cc clusters.Clusters, frozen []bool, sizes []int) int {
+	var n int
+	for ci, c := range cc {
+		size := len(c.Observations)
+		if sizes != nil {
+			size = sizes[ci]
+		}
+		if size > 1 || (frozen != nil && frozen[ci]) {
+			n++
+		}
+	}
+	return n
+}
+
+// centersMoved returns whether any of the cluster centers differs from the
+// given previous centers
+func centersMoved(cc clusters.Clusters, prev []clusters.Coordinates) bool {
+	for ci := range cc {
+		for j, v := range cc[ci].Center {
+			if v != prev[ci][j] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyCenters returns copies of the centers of the clusters
+func copyCenters(cc clusters.Clusters) []clusters.Coordinates {
+	centers := make([]clusters.Coordinates, len(cc))
+	for ci, c := range cc {
+		centers[ci] = append(clusters.Coordinates{}, c.Center...)
+	}
+	return centers
+}
+
+// copyClusters copies the centers and members of src into dst, reusing the
+// storage of dst where possible, and returns dst
+func copyClusters(dst, src clusters.Clusters) clusters.Clusters {
+	if cap(dst) < len(src) {
+		dst = append(dst[:cap(dst)], make(clusters.Clusters, len(src)-cap(dst))...)
+	}
+	dst = dst[:len(src)]
+	for ci := range src {
+		dst[ci].Center = append(dst[ci].Center[:0], src[ci].Center...)
+		dst[ci].Observations = append(dst[ci].Observations[:0], src[ci].Observations...)
+	}
+	return dst
+}
+
+// reassign assigns every data point to its nearest cluster, replacing the
+// current members of all clusters
+func (m Kmeans) reassign(cc clusters.Clusters, dataset clusters.Observations, assignment []int) {
+	m.forEach(len(dataset), func(i int) {
+		assignment[i], _ = m.nearest(cc, dataset[i])
+	})
+
+	cc.Reset()
+	for i, ci := range assignment {
+		cc[ci].Append(dataset[i])
+	}
+}
+
+// snapToData moves each cluster center to the data point nearest to it,
+// only considering the CandidatePool if set
+func (m Kmeans) snapToData(cc clusters.Clusters, dataset clusters.Observations) {
+	frozen := m.frozen(len(cc))
+	m.forEach(len(cc), func(ci int) {
+		if frozen != nil && frozen[ci] {
+			return
+		}
+		nearest, dist := -1, -1.0
+		m.eachCandidate(len(dataset), func(i int) {
+			if d := m.distance(dataset[i], cc[ci].Center); dist < 0 || d < dist {
+				nearest, dist = i, d
+			}
+		})
+		cc[ci].Center = append(clusters.Coordinates{}, dataset[nearest].Coordinates()...)
+	})
+}
+
+// eachCandidate calls fn with the index of every data point in the
+// CandidatePool, or every data point of the dataset if there is no pool
+func (m Kmeans) eachCandidate(n int, fn func(i int)) {
+	if m.CandidatePool != nil {
+		for _, i := range m.CandidatePool {
+			fn(i)
+		}
+		return
+	}
+	for i := 0; i < n; i++ {
+		fn(i)
+	}
+}
+
+// Recenter computes the clusters of an externally computed assignment of the
+// dataset to k clusters, the same way Partition does after each assignment
+// step: every cluster's center is the (weighted) mean of its members. Empty
+// clusters get refilled with a data point of another cluster, as selected by
+// Refill, in which case assignment gets updated accordingly
+func (m Kmeans) Recenter(dataset clusters.Observations, assignment []int, k int) (clusters.Clusters, error) {
+	if len(dataset) == 0 || len(dataset[0].Coordinates()) == 0 {
+		return nil, fmt.Errorf("there must be at least one dimension in the data set")
+	}
+	if k <= 0 || k > len(dataset) {
+		return nil, fmt.Errorf("k is out of bounds (must be between 1 and the size of the data set)")
+	}
+	if len(assignment) != len(dataset) {
+		return nil, fmt.Errorf("the size of the assignment must equal the size of the data set")
+	}
+	if m.Weights != nil && len(m.Weights) != len(dataset) {
+		return nil, fmt.Errorf("the number of weights must equal the size of the data set")
+	}
+	if err := m.checkCenter(dataset); err != nil {
+		return nil, err
+	}
+
+	cc := make(clusters.Clusters, k)
+	for ci := range cc {
+		cc[ci].Center = make(clusters.Coordinates, len(dataset[0].Coordinates()))
+	}
+	for i, ci := range assignment {
+		if ci < 0 || ci >= k {
+			return nil, fmt.Errorf("cluster %d of data point %d is out of bounds (must be between 0 and k-1)", ci, i)
+		}
+		cc[ci].Append(dataset[i])
+	}
+
+	// there are no initial centers to keep
+	m.FrozenCentroids = nil
+
+	rng := m.rand()
+	m.refillEmpty(cc, dataset, assignment, rng, nil)
+	m.recenter(cc, dataset, assignment)
+
 	return cc, nil
 }
+
+// randMu guards the configured sources of randomness, as a rand.Rand isn't
+// safe for concurrent use
+var randMu sync.Mutex
+
+// rand returns a source of randomness for a single call, seeded from the
+// configured source or, if there is none, the global one
+func (m Kmeans) rand() *rand.Rand {
+	if m.Rand != nil {
+		randMu.Lock()
+		seed := m.Rand.Int63()
+		randMu.Unlock()
+		return rand.New(rand.NewSource(seed)) //nolint:gosec // math/rand is good enough for this
+	}
+	return rand.New(rand.NewSource(rand.Int63())) //nolint:gosec // math/rand is good enough for this
+}
+
+// frozen returns which of the k clusters are frozen, or nil if none are
+func (m Kmeans) frozen(k int) []bool {
+	if len(m.FrozenCentroids) == 0 {
+		return nil
+	}
+	frozen := make([]bool, k)
+	for _, ci := range m.FrozenCentroids {
+		if ci < k {
+			frozen[ci] = true
+		}
+	}
+	return frozen
+}
+
+// recenterChunkSize is the number of cluster members summed up by a single
+// task when recentering, so the members of large clusters get spread across
+// threads
+const recenterChunkSize = 1024
+
+// recenter moves the center of each cluster to the (weighted) mean of its
+// members, the data points assigned to it. Clusters get split into chunks
+// of fixed size, so the work is balanced across threads even if a few
+// clusters hold most data points. Clusters without members (or weight)
+// and frozen clusters keep their center. A configured Aggregator computes
+// the centers instead
+func (m Kmeans) recenter(cc clusters.Clusters, dataset clusters.Observations, assignment []int) {
+	if m.Aggregator != nil {
+		m.aggregate(cc)
+		if m.Spherical {
+			normalizeCenters(cc, m.frozen(len(cc)))
+		}
+		m.constrain(cc, m.frozen(len(cc)))
+		return
+	}
+
+	// the assignment is the single source of membership, with or without
+	// weights, and orders the members the same regardless of the threads
+	members := make([][]int, len(cc))
+	for i, ci := range assignment {
+		members[ci] = append(members[ci], i)
+	}
+	size := func(ci int) int {
+		return len(members[ci])
+	}
+
+	type task struct {
+		ci, start, end int
+	}
+	frozen := m.frozen(len(cc))
+	var tasks []task
+	for ci := range cc {
+		if frozen != nil && frozen[ci] {
+			continue
+		}
+		for start := 0; start < size(ci); start += recenterChunkSize {
+			end := start + recenterChunkSize
+			if end > size(ci) {
+				end = size(ci)
+			}
+			tasks = append(tasks, task{ci, start, end})
+		}
+	}
+
+	sums := make([][]float64, len(tasks))
+	totals := make([]float64, len(tasks))
+	m.forEach(len(tasks), func(t int) {
+		tt := tasks[t]
+		sum := make([]float64, len(cc[tt.ci].Center))
+		var total float64
+		for n := tt.start; n < tt.end; n++ {
+			i := members[tt.ci][n]
+			w := 1.0
+			if m.Weights != nil {
+				w = m.Weights[i]
+			}
+
+			for j, v := range dataset[i].Coordinates() {
+				sum[j] += w * v
+			}
+			total += w
+		}
+		sums[t], totals[t] = sum, total
+	})
+
+	for t := 0; t < len(tasks); {
+		ci := tasks[t].ci
+		center := make(clusters.Coordinates, len(cc[ci].Center))
+		var total float64
+		for ; t < len(tasks) && tasks[t].ci == ci; t++ {
+			for j, v := range sums[t] {
+				center[j] += v
+			}
+			total += totals[t]
+		}
+		if total == 0 {
+			continue
+		}
+
+		for j := range center {
+			center[j] /= total
+		}
+		cc[ci].Center = center
+	}
+
+	if m.Center == CenterPrototype {
+		m.recenterModes(cc, dataset, members, frozen)
+	}
+	if m.Spherical {
+		normalizeCenters(cc, frozen)
+	}
+	m.constrain(cc, frozen)
+}
+
+// budgetSpent returns whether the distance evaluations of the call reached
+// the MaxDistanceEvals
+func (m Kmeans) budgetSpent() bool {
+	return m.MaxDistanceEvals > 0 && m.distances != nil && m.distances.Load() >= uint64(m.MaxDistanceEvals)
+}
+
+// iterationInertia returns whether any of the configured options, or a
+// stream of snapshots, requires computing the inertia after each iteration
+func (m Kmeans) iterationInertia() bool {
+	return m.TargetInertia > 0 || m.snapshots != nil
+}
+
+// inertiaChunkSize is the number of data points whose squared distances get
+// summed up sequentially when computing the inertia
+const inertiaChunkSize = 1024
+
+// inertia returns the sum of squared distances of the data points to the
+// center of their assigned cluster. If weights are configured, each squared
+// distance is multiplied by the weight of its data point. The data points
+// get summed up in parallel, in chunks of inertiaChunkSize
+func (m Kmeans) inertia(dataset clusters.Observations, assignment []int, cc clusters.Clusters) float64 {
+	sums := make([]float64, (len(dataset)+inertiaChunkSize-1)/inertiaChunkSize)
+	m.forEach(len(sums), func(chunk int) {
+		end := (chunk + 1) * inertiaChunkSize
+		if end > len(dataset) {
+			end = len(dataset)
+		}
+
+		var sum float64
+		for i := chunk * inertiaChunkSize; i < end; i++ {
+			d := m.distance(dataset[i], cc[assignment[i]].Center)
+			if m.Weights != nil {
+				d *= m.Weights[i]
+			}
+			sum += d
+		}
+		sums[chunk] = sum
+	})
+
+	var sum float64
+	for _, s := range sums {
+		sum += s
+	}
+	return sum
+}
+
+// seedFromLabels moves the center of every labeled cluster to the mean of
+// its labeled data points
+func (m Kmeans) seedFromLabels(cc clusters.Clusters, dataset clusters.Observations) error {
+	if len(m.InitLabels) != len(dataset) {
+		return fmt.Errorf("the number of init labels must equal the size of the data set")
+	}
+
+	groups := make([]clusters.Observations, len(cc))
+	for i, l := range m.InitLabels {
+		if l == -1 {
+			continue
+		}
+		if l < 0 || l >= len(cc) {
+			return fmt.Errorf("init label %d of data point %d is out of bounds (must be -1 or between 0 and k-1)", l, i)
+		}
+		groups[l] = append(groups[l], dataset[i])
+	}
+
+	for ci, g := range groups {
+		if len(g) == 0 {
+			continue
+		}
+		center, err := g.Center()
+		if err != nil {
+			return err
+		}
+		cc[ci].Center = center
+	}
+	return nil
+}
@@ -1,10 +1,17 @@
 package kmeans
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/k---n/clusters"
+	"github.com/k----n/clusters"
 )
 
 const (
@@ -23,6 +30,29 @@ func TestNewErrors(t *testing.T) {
 	}
 }
 
+func TestMaxIterations(t *testing.T) {
+	if n := New().MaxIterations(); n != DefaultMaxIterations {
+		t.Errorf("Expected %d iterations by default, got %d", DefaultMaxIterations, n)
+	}
+	if n := (Kmeans{}).MaxIterations(); n != 0 {
+		t.Errorf("Expected a zero iteration threshold for a zero Kmeans, got %d", n)
+	}
+
+	// which stops after the first iteration
+	var d clusters.Observations
+	for i := 0; i < 16; i++ {
+		d = append(d, clusters.Coordinates{float64(i), float64(i % 4)})
+	}
+	res, err := (Kmeans{}).Fit(d, 4)
+	if err != nil {
+		t.Errorf("Unexpected error fitting: %v", err)
+		return
+	}
+	if res.Iterations != 1 {
+		t.Errorf("Expected a single iteration for a zero Kmeans, got %d", res.Iterations)
+	}
+}
+
 func TestPartitioningError(t *testing.T) {
 	km := New()
 	d := clusters.Observations{}
@@ -48,6 +78,75 @@ func TestPartitioningError(t *testing.T) {
 	}
 }
 
+func TestAsManyClustersAsDataPoints(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0.9, 0.1},
+		clusters.Coordinates{0.1, 0.1},
+		clusters.Coordinates{0.5, 0.9},
+	}
+
+	km := New()
+	km.Init = InitKMeansPlusPlus
+	res, err := km.Fit(d, 3)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	for ci, c := range res.Clusters {
+		if len(c.Observations) != 1 || !reflect.DeepEqual(c.Center, d[ci]) || res.Assignment[ci] != ci {
+			t.Errorf("Expected data point %d in a cluster of its own, got %+v", ci, c)
+		}
+	}
+	if res.Inertia != 0 || res.Iterations != 0 || !res.Converged {
+		t.Errorf("Expected a converged fit without iterations nor inertia, got %+v", res)
+	}
+
+	// the trivial partition doesn't share the storage of the dataset
+	res.Clusters[0].Center[0] = 0
+	if d[0].Coordinates()[0] != 0.9 {
+		t.Errorf("Expected the dataset to be left unchanged, got %v", d[0])
+	}
+
+	// one data point fewer than clusters
+	if _, err := km.Partition(d[:2], 3); err == nil {
+		t.Errorf("Expected error partitioning with more clusters than data points, got nil")
+	}
+	// one data point more than clusters
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(cc[0].Observations)+len(cc[1].Observations) != 3 {
+		t.Errorf("Expected all data points partitioned, got %v", cc)
+	}
+}
+
+func TestTooFewDistinct(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 100; i++ {
+		d = append(d,
+			clusters.Coordinates{0.1, 0.1},
+			clusters.Coordinates{0.5, 0.5},
+			clusters.Coordinates{0.9, 0.9})
+	}
+
+	km := New()
+	if _, err := km.Partition(d, 4); err == nil {
+		t.Errorf("Expected error partitioning with fewer distinct data points than clusters, got nil")
+	}
+	cc, err := km.Partition(d, 3)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	for _, c := range cc {
+		if len(c.Observations) != 100 {
+			t.Errorf("Expected 100 data points per cluster, got %d", len(c.Observations))
+		}
+	}
+}
+
 func TestDimensions(t *testing.T) {
 	var d clusters.Observations
 	for x := 0; x < 255; x += 32 {
@@ -72,24 +171,1281 @@ func TestDimensions(t *testing.T) {
 	}
 }
 
-func benchmarkPartition(size, partitions int, b *testing.B) {
+func TestInitLabels(t *testing.T) {
+	var d clusters.Observations
+	labels := []int{}
+	for i := 0; i < 32; i++ {
+		d = append(d, clusters.Coordinates{0.1 + float64(i%4)*0.01, 0.1})
+		d = append(d, clusters.Coordinates{0.9 - float64(i%4)*0.01, 0.9})
+		if i < 2 {
+			labels = append(labels, 1, 0)
+		} else {
+			labels = append(labels, -1, -1)
+		}
+	}
+
+	km := New()
+	km.InitLabels = labels
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	if cc[0].Center[0] < 0.5 || cc[1].Center[0] > 0.5 {
+		t.Errorf("Expected clusters to be anchored to their labeled groups, got centers %v and %v",
+			cc[0].Center, cc[1].Center)
+	}
+
+	km.InitLabels = labels[1:]
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with mismatching init labels, got nil")
+	}
+
+	km.InitLabels = append([]int{2}, labels[1:]...)
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with out of bounds init labels, got nil")
+	}
+}
+
+func TestFailOnNoProgress(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0}, clusters.Coordinates{1}, clusters.Coordinates{2}, clusters.Coordinates{3},
+		clusters.Coordinates{10}, clusters.Coordinates{11}, clusters.Coordinates{12}, clusters.Coordinates{13},
+	}
+
+	// labels of the optimal partition seed the final centers
+	km := New()
+	km.InitLabels = []int{0, 0, 0, 0, 1, 1, 1, 1}
+	if _, err := km.Partition(d, 2); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+	}
+	km.FailOnNoProgress = true
+	if _, err := km.Partition(d, 2); err != ErrNoProgress {
+		t.Errorf("Expected ErrNoProgress, got %v", err)
+	}
+
+	km.InitLabels = []int{0, 0, 1, 0, 1, 1, 1, 1}
+	if _, err := km.Partition(d, 2); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+	}
+
+	// the second center only gets normalized, in place, as its member has
+	// no weight
+	km = New()
+	km.FailOnNoProgress = true
+	km.Spherical = true
+	km.Init = WarmStart{{0, 1}, {2, 0}}
+	km.Weights = []float64{1, 1, 0}
+	sd := clusters.Observations{clusters.Coordinates{0, 1}, clusters.Coordinates{0, 1}, clusters.Coordinates{1, 0}}
+	if _, err := km.Partition(sd, 2); err != nil {
+		t.Errorf("Unexpected error partitioning with a normalized center: %v", err)
+	}
+}
+
+func TestAbortIfOverK(t *testing.T) {
+	// two clumps of duplicates and a single point in between: the third
+	// cluster, seeded far off, almost always gets refilled with one of the
+	// duplicates, whose cluster then wins the tie
+	var d clusters.Observations
+	for i := 0; i < 2048; i++ {
+		d = append(d, clusters.Coordinates{float64(i%2) * 10})
+	}
+	d = append(d, clusters.Coordinates{5})
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Init = anchors{{0}, {10}, {100}}
+	if _, err := km.Partition(d, 3); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+	}
+
+	km.AbortIfOverK = true
+	_, err := km.Partition(d, 3)
+	if e, ok := err.(ErrKTooLarge); !ok || e.K != 3 || e.NonEmpty != 2 {
+		t.Errorf("Expected ErrKTooLarge with 2 non-empty clusters, got %v", err)
+	}
+	km.Init = InitForgy
+	if _, err := km.Partition(d, 2); err != nil {
+		t.Errorf("Unexpected error partitioning into 2 clusters: %v", err)
+	}
+}
+
+func TestFit(t *testing.T) {
 	rand.Seed(randomSeed)
 	var d clusters.Observations
+	for i := 0; i < 512; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
 
-	for i := 0; i < size; i++ {
+	p := &countingPlotter{}
+	km, _ := NewWithOptions(0.01, p)
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.CountDistances = true
+	r, err := km.Fit(d, 8)
+	if err != nil {
+		t.Errorf("Unexpected error fitting: %v", err)
+		return
+	}
+	if len(r.Clusters) != 8 || len(r.Assignment) != len(d) {
+		t.Errorf("Expected 8 clusters and %d assignments, got %d and %d", len(d), len(r.Clusters), len(r.Assignment))
+	}
+	if inertia := km.inertia(d, r.Assignment, r.Clusters); r.Inertia != inertia {
+		t.Errorf("Expected inertia %f, got %f", inertia, r.Inertia)
+	}
+	if r.Iterations != p.plots || !r.Converged {
+		t.Errorf("Expected a converged run of %d iterations, got %d (%t)", p.plots, r.Iterations, r.Converged)
+	}
+	if r.Stats.DistanceEvaluations != uint64(r.Iterations*len(d)*8) {
+		t.Errorf("Expected %d distance evaluations, got %+v", r.Iterations*len(d)*8, r.Stats)
+	}
+
+	// a seed far off the data ends up empty
+	km.Init = anchors{{0.25, 0.5}, {0.75, 0.5}, {100, 100}}
+	if r, _ := km.Fit(d, 3); r.EmptyClusters == 0 {
+		t.Errorf("Expected an empty cluster to be refilled, got %d", r.EmptyClusters)
+	}
+
+	km.Init = InitRandom
+	km.iterationThreshold = 1
+	km.deltaThreshold = 1e-9
+	if r, _ := km.Fit(d, 8); r.Converged || r.Iterations != 2 {
+		t.Errorf("Expected an unconverged run of 2 iterations, got %d (%t)", r.Iterations, r.Converged)
+	}
+}
+
+func TestRefit(t *testing.T) {
+	d, _ := MakeBlobs(512, 4, 2, 0.5, rand.New(rand.NewSource(randomSeed)))
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Init = InitKMeansPlusPlus
+	prev, err := km.Partition(d[:256], 4)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	// the grown dataset has the same blobs, which keep their indices
+	cc, err := km.Refit(prev, d)
+	if err != nil {
+		t.Errorf("Unexpected error refitting: %v", err)
+		return
+	}
+	for ci := range prev {
+		if dist := prev[ci].Center.Distance(cc[ci].Center); dist > 0.1 {
+			t.Errorf("Expected cluster %d to stay in place, moved from %v to %v", ci, prev[ci].Center, cc[ci].Center)
+		}
+	}
+	if len(cc[0].Observations)+len(cc[1].Observations)+len(cc[2].Observations)+len(cc[3].Observations) != len(d) {
+		t.Errorf("Expected all %d observations to be assigned", len(d))
+	}
+
+	if _, err := km.Refit(nil, d); err == nil {
+		t.Errorf("Expected error refitting without previous clusters, got nil")
+	}
+	if _, err := km.Refit(clusters.Clusters{{Center: clusters.Coordinates{0}}}, d); err == nil {
+		t.Errorf("Expected error refitting with mismatching dimensions, got nil")
+	}
+}
+
+func TestRestartOnThrash(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 512; i++ {
 		d = append(d, clusters.Coordinates{
 			rand.Float64(),
 			rand.Float64(),
 		})
 	}
 
-	for j := 0; j < b.N; j++ {
+	k := 8
+	km := New()
+	km.NInit = 2
+	km.RestartOnThrash = true
+	km.ThrashWindow = 1
+	km.MaxRestarts = 2
+	cc, err := km.Partition(d, k)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(cc) != k {
+		t.Errorf("Expected %d clusters, got: %d", k, len(cc))
+	}
+
+	var n int
+	for _, c := range cc {
+		n += len(c.Observations)
+	}
+	if n < len(d) {
+		t.Errorf("Expected all %d data points to be assigned, got %d", len(d), n)
+	}
+}
+
+func TestReproducibleEmptyClusters(t *testing.T) {
+	// a dense data set in a corner of the unit square leaves most of the
+	// randomly seeded clusters empty after the first iteration
+	var d clusters.Observations
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			d = append(d, clusters.Coordinates{
+				float64(x) / 1024.0,
+				float64(y) / 1024.0,
+			})
+		}
+	}
+
+	partition := func() clusters.Clusters {
 		km := New()
-		km.Partition(d, partitions)
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		cc, err := km.Partition(d, 8)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc
+	}
+
+	c1, c2 := partition(), partition()
+	for i := range c1 {
+		if !reflect.DeepEqual(c1[i].Center, c2[i].Center) {
+			t.Errorf("Expected identical centers for the same seed, got %v and %v", c1[i].Center, c2[i].Center)
+		}
 	}
 }
 
-func BenchmarkPartition32Points(b *testing.B)    { benchmarkPartition(32, 16, b) }
-func BenchmarkPartition512Points(b *testing.B)   { benchmarkPartition(512, 16, b) }
-func BenchmarkPartition4096Points(b *testing.B)  { benchmarkPartition(4096, 16, b) }
-func BenchmarkPartition65536Points(b *testing.B) { benchmarkPartition(65536, 16, b) }
+func TestPartitionInto(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 512; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	km := New()
+	km.NInit = 3
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	cc, err := km.Partition(d, 8)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	// a too small buffer gets grown, a large enough one reused
+	var dst clusters.Clusters
+	assignment := make([]int, len(d))
+	for run := 0; run < 2; run++ {
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		if err := km.PartitionInto(&dst, assignment, d, 8); err != nil {
+			t.Errorf("Unexpected error partitioning: %v", err)
+			return
+		}
+		if !reflect.DeepEqual(dst, cc) {
+			t.Errorf("Expected the same clusters as Partition, got %v", dst)
+		}
+		sizes := make([]int, len(dst))
+		for _, ci := range assignment {
+			sizes[ci]++
+		}
+		for ci, c := range dst {
+			if sizes[ci] != len(c.Observations) {
+				t.Errorf("Expected %d data points assigned to cluster %d, got %d", len(c.Observations), ci, sizes[ci])
+			}
+		}
+	}
+
+	reused := &dst[0]
+	if err := km.PartitionInto(&dst, nil, d, 4); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+	}
+	if len(dst) != 4 || &dst[0] != reused {
+		t.Errorf("Expected the buffer to be reused for 4 clusters, got %d clusters", len(dst))
+	}
+
+	if err := km.PartitionInto(&dst, make([]int, 1), d, 8); err == nil {
+		t.Errorf("Expected error partitioning into a too small assignment, got nil")
+	}
+}
+
+func TestPartitionReusing(t *testing.T) {
+	d, _ := MakeBlobs(512, 8, 2, 1, rand.New(rand.NewSource(randomSeed)))
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	exp, err := km.Partition(d, 8)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	var cc clusters.Clusters
+	for run := 0; run < 2; run++ {
+		prev := cc
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		if cc, err = km.PartitionReusing(cc, d, 8); err != nil {
+			t.Errorf("Unexpected error partitioning: %v", err)
+			return
+		}
+		if !reflect.DeepEqual(cc, exp) {
+			t.Errorf("Expected the same clusters as Partition, got %v", cc)
+		}
+		if prev != nil && &cc[0] != &prev[0] {
+			t.Errorf("Expected the clusters to be reused")
+		}
+	}
+}
+
+func TestConcurrentPartition(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	// concurrent calls sharing a source get the same seeds as sequential
+	// ones, just in a different order
+	const calls = 8
+	centers := func(cc clusters.Clusters) string {
+		return fmt.Sprint(cc[0].Center)
+	}
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	var want []string
+	for i := 0; i < calls; i++ {
+		cc, _ := km.Partition(d, 4)
+		want = append(want, centers(cc))
+	}
+
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	got := make([]string, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cc, err := km.Partition(d, 4)
+			if err != nil {
+				t.Errorf("Unexpected error partitioning: %v", err)
+				return
+			}
+			got[i] = centers(cc)
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Strings(want)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the results of sequential calls %v, got %v", want, got)
+	}
+}
+
+type movementPlotter struct {
+	plots, moves int
+	last         []clusters.Coordinates
+	err          error
+}
+
+func (p *movementPlotter) Plot(cc clusters.Clusters, iteration int) error {
+	p.plots++
+	return nil
+}
+
+func (p *movementPlotter) PlotWithMovement(cc clusters.Clusters, prev []clusters.Coordinates, iteration int) error {
+	p.moves++
+	if p.last != nil && !reflect.DeepEqual(prev, p.last) && p.err == nil {
+		p.err = fmt.Errorf("expected previous centers %v, got %v", p.last, prev)
+	}
+	p.last = nil
+	for _, c := range cc {
+		p.last = append(p.last, append(clusters.Coordinates{}, c.Center...))
+	}
+	return nil
+}
+
+func TestMovementPlotter(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 64; i++ {
+		d = append(d, clusters.Coordinates{float64(i%8) / 8.0, float64(i/8) / 8.0})
+	}
+
+	p := &movementPlotter{}
+	km, _ := NewWithOptions(0.01, p)
+	if _, err := km.Partition(d, 4); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	if p.plots != 0 || p.moves == 0 {
+		t.Errorf("Expected only PlotWithMovement to be called, got %d plots and %d moves", p.plots, p.moves)
+	}
+	if p.err != nil {
+		t.Error(p.err)
+	}
+}
+
+type countingPlotter struct {
+	plots int
+}
+
+func (p *countingPlotter) Plot(cc clusters.Clusters, iteration int) error {
+	p.plots++
+	return nil
+}
+
+func TestSnapToData(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	km := New()
+	km.SnapToData = true
+	cc, err := km.Partition(d, 8)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	observed := make(map[string]bool)
+	for _, o := range d {
+		observed[fmt.Sprint(o.Coordinates())] = true
+	}
+	var n int
+	for ci, c := range cc {
+		if !observed[fmt.Sprint(c.Center)] {
+			t.Errorf("Expected center %v to be an actual data point", c.Center)
+		}
+		for _, o := range c.Observations {
+			if km.Predict(cc, o) != ci {
+				t.Errorf("Expected data point %v to be reassigned to its nearest center", o)
+			}
+		}
+		n += len(c.Observations)
+	}
+	if n != len(d) {
+		t.Errorf("Expected %d reassigned data points, got %d", len(d), n)
+	}
+}
+
+func TestTargetInertia(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 1024; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	p := &countingPlotter{}
+	km, _ := NewWithOptions(0.0001, p)
+	km.TargetInertia = float64(len(d))
+	if _, err := km.Partition(d, 16); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if p.plots != 1 {
+		t.Errorf("Expected a reachable target to stop after the first iteration, got %d iterations", p.plots)
+	}
+
+	// an unreachable target falls through to the regular stop criteria
+	p.plots = 0
+	km.TargetInertia = 1e-12
+	if _, err := km.Partition(d, 16); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if p.plots < 2 {
+		t.Errorf("Expected an unreachable target to keep iterating, got %d iterations", p.plots)
+	}
+}
+
+func TestLazyInertia(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	// a single run has nothing to compare its inertia against
+	km := New()
+	res, err := km.partition(d, 4)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if !math.IsNaN(res.inertia) {
+		t.Errorf("Expected no inertia for a single run, got %f", res.inertia)
+	}
+
+	km.NInit = 2
+	if res, _ = km.partition(d, 4); math.Abs(res.inertia-km.inertia(d, res.assignment, res.clusters)) > 1e-9 {
+		t.Errorf("Expected the inertia of the best run, got %f", res.inertia)
+	}
+}
+
+func TestFeatureWeights(t *testing.T) {
+	// two groups along x, hidden by a much larger spread along y
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		d = append(d, clusters.Coordinates{
+			float64(i%2) + rand.Float64()*0.1,
+			rand.Float64() * 10,
+		})
+	}
+
+	km := New()
+	km.Init = InitKMeansPlusPlus
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.FeatureWeights = []float64{1, 0}
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	for i, ci := range km.PredictAll(cc, d) {
+		if ci != km.Predict(cc, d[i%2]) {
+			t.Errorf("Expected data point %d to be grouped by its x coordinate", i)
+			break
+		}
+	}
+	if in := km.inertia(d, km.PredictAll(cc, d), cc); in > float64(len(d))*0.01 {
+		t.Errorf("Expected the inertia to ignore the y coordinate, got %f", in)
+	}
+
+	km.FeatureWeights = []float64{1}
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with mismatching feature weights, got nil")
+	}
+	km.FeatureWeights = []float64{1, -1}
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with negative feature weights, got nil")
+	}
+}
+
+func TestFrozenCentroids(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 64; i++ {
+		x := float64(i) / 63.0
+		d = append(d, clusters.Coordinates{x * x})
+	}
+
+	// the bounding box seeds the centers at 0.25 and 0.75
+	km := New()
+	km.Init = InitBoundingBox
+	km.FrozenCentroids = []int{0}
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if cc[0].Center[0] != 0.25 {
+		t.Errorf("Expected the frozen center to stay at 0.25, got %v", cc[0].Center)
+	}
+	if cc[1].Center[0] == 0.75 || len(cc[0].Observations) == 0 {
+		t.Errorf("Expected the other center to move while both get assigned data points, got %v", cc)
+	}
+
+	km.FrozenCentroids = []int{2}
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with an out of bounds frozen centroid, got nil")
+	}
+}
+
+func TestCountDistances(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	p := &countingPlotter{}
+	km, _ := NewWithOptions(0.01, p)
+	km.Init = InitForgy
+	if _, stats, _ := km.PartitionWithStats(d, 4); stats.DistanceEvaluations != 0 {
+		t.Errorf("Expected no distances to be counted by default, got %d", stats.DistanceEvaluations)
+	}
+
+	// Forgy seeding doesn't compute any distances, so all of them are
+	// spent on assigning each data point to one of 4 clusters
+	p.plots = 0
+	km.CountDistances = true
+	_, stats, err := km.PartitionWithStats(d, 4)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if want := uint64(p.plots * len(d) * 4); stats.DistanceEvaluations != want {
+		t.Errorf("Expected %d distance evaluations in %d iterations, got %d", want, p.plots, stats.DistanceEvaluations)
+	}
+}
+
+func TestTrackChanges(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 512; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	p := &countingPlotter{}
+	km, _ := NewWithOptions(0.0001, p)
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	if _, stats, _ := km.PartitionWithStats(d, 8); stats.LastChanged != nil {
+		t.Errorf("Expected no changes to be tracked by default, got %v", stats.LastChanged)
+	}
+
+	p.plots = 0
+	km.TrackChanges = true
+	_, stats, err := km.PartitionWithStats(d, 8)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(stats.LastChanged) != len(d) {
+		t.Errorf("Expected the last change of %d data points, got %d", len(d), len(stats.LastChanged))
+		return
+	}
+	var latest int
+	for i, it := range stats.LastChanged {
+		if it < 0 || it >= p.plots {
+			t.Errorf("Expected data point %d to change within %d iterations, got %d", i, p.plots, it)
+		}
+		if it > latest {
+			latest = it
+		}
+	}
+	// the run only stops once no data point shifts clusters anymore
+	if latest == 0 || latest < p.plots-2 {
+		t.Errorf("Expected the last change in one of the final iterations, got %d of %d", latest, p.plots)
+	}
+}
+
+func TestStableWindow(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 1024; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	iterations := func(window int) int {
+		p := &countingPlotter{}
+		km, _ := NewWithOptions(0.05, p)
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.StableWindow = window
+		if _, err := km.Partition(d, 16); err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return p.plots
+	}
+
+	one := iterations(1)
+	if n := iterations(0); n != one {
+		t.Errorf("Expected a window of 0 to behave like a window of 1, got %d and %d iterations", n, one)
+	}
+	if n := iterations(3); n < one+2 {
+		t.Errorf("Expected a window of 3 to run at least 2 more iterations than %d, got %d", one, n)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 1024; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	p := &countingPlotter{}
+	km, _ := NewWithOptions(0.0001, p)
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.CheckpointEvery = 3
+	var checkpoints []int
+	km.Checkpoint = func(iteration int, cc clusters.Clusters, assignment []int) error {
+		checkpoints = append(checkpoints, iteration)
+		if len(cc) != 16 || len(assignment) != len(d) {
+			t.Errorf("Expected 16 clusters and %d assignments, got %d and %d", len(d), len(cc), len(assignment))
+		}
+		return nil
+	}
+	if _, err := km.Partition(d, 16); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(checkpoints) != p.plots/3 {
+		t.Errorf("Expected %d checkpoints in %d iterations, got %v", p.plots/3, p.plots, checkpoints)
+	}
+	for n, it := range checkpoints {
+		if it != 3*n+2 {
+			t.Errorf("Expected checkpoint %d at iteration %d, got %d", n, 3*n+2, it)
+		}
+	}
+
+	failed := errors.New("disk full")
+	km.Checkpoint = func(iteration int, cc clusters.Clusters, assignment []int) error {
+		return failed
+	}
+	if _, err := km.Partition(d, 16); err != failed {
+		t.Errorf("Expected the error of the checkpoint, got %v", err)
+	}
+}
+
+func TestMaxDistanceEvals(t *testing.T) {
+	d, _ := MakeBlobs(1024, 8, 2, 3, rand.New(rand.NewSource(randomSeed)))
+
+	fit := func(budget int) Result {
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.NInit = 4
+		km.CountDistances = true
+		km.MaxDistanceEvals = budget
+		res, err := km.Fit(d, 8)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return res
+	}
+
+	full := fit(0)
+	// enough for a few iterations of the first run
+	budget := 5 * len(d) * 8
+	res := fit(budget)
+	if res.Converged || res.Iterations >= full.Iterations {
+		t.Errorf("Expected fewer than %d iterations without converging, got %d", full.Iterations, res.Iterations)
+	}
+	if evals := res.Stats.DistanceEvaluations; evals < uint64(budget) || evals > uint64(budget+2*len(d)*8) {
+		t.Errorf("Expected about %d distance evaluations, got %d", budget, evals)
+	}
+	if evals := full.Stats.DistanceEvaluations; evals <= uint64(budget) {
+		t.Errorf("Expected more than %d distance evaluations without a budget, got %d", budget, evals)
+	}
+}
+
+func TestPolish(t *testing.T) {
+	d, _ := MakeBlobs(2048, 8, 2, 3, rand.New(rand.NewSource(randomSeed)))
+
+	// a loose delta threshold stops well before the fixed point
+	fit := func(polish bool) Result {
+		km, _ := NewWithOptions(0.2, nil)
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Polish = polish
+		res, err := km.Fit(d, 8)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return res
+	}
+
+	rough, polished := fit(false), fit(true)
+	if polished.Iterations <= rough.Iterations || polished.Inertia > rough.Inertia {
+		t.Errorf("Expected more iterations than %d to a lower inertia than %f, got %d and %f",
+			rough.Iterations, rough.Inertia, polished.Iterations, polished.Inertia)
+	}
+	if changed, _ := New().Step(polished.Clusters, d, polished.Assignment); changed != 0 {
+		t.Errorf("Expected a fixed point, got %d data points shifting clusters", changed)
+	}
+
+	km, _ := NewWithOptions(0.2, nil)
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Polish = true
+	km.PolishMaxIterations = 1
+	if res, err := km.Fit(d, 8); err != nil || res.Iterations != rough.Iterations+1 {
+		t.Errorf("Expected a single polishing iteration after %d, got %d (%v)", rough.Iterations, res.Iterations, err)
+	}
+
+	// a zero Kmeans stops after its first iteration, and polishes a few more
+	zero := Kmeans{Polish: true}
+	if n := zero.polishMaxIterations(); n != DefaultPolishFactor {
+		t.Errorf("Expected at most %d polishing iterations for a zero Kmeans, got %d", DefaultPolishFactor, n)
+	}
+	if res, err := zero.Fit(d, 8); err != nil || res.Iterations > 1+DefaultPolishFactor {
+		t.Errorf("Expected at most %d iterations for a zero Kmeans, got %d (%v)", 1+DefaultPolishFactor, res.Iterations, err)
+	}
+}
+
+func TestPolishTerminates(t *testing.T) {
+	d, _ := MakeBlobs(400, 4, 2, 0.3, rand.New(rand.NewSource(randomSeed)))
+
+	done := make(chan error, 1)
+	go func() {
+		// order-dependent assignments, which may never settle
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Polish = true
+		km.BalancePenalty = 0.05
+		km.ShuffleEachIteration = true
+		_, err := km.Fit(d, 4)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error partitioning: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Errorf("Expected polishing to terminate")
+	}
+}
+
+func TestMaxDuration(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 4096; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	p := &countingPlotter{}
+	km, _ := NewWithOptions(0.0001, p)
+	km.NInit = 100
+	km.MaxDuration = time.Nanosecond
+	cc, err := km.Partition(d, 16)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(cc) != 16 {
+		t.Errorf("Expected 16 clusters, got: %d", len(cc))
+	}
+	if p.plots != 1 {
+		t.Errorf("Expected a spent budget to stop after the first iteration, got %d iterations", p.plots)
+	}
+}
+
+func TestStep(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0}, clusters.Coordinates{1}, clusters.Coordinates{2}, clusters.Coordinates{3},
+		clusters.Coordinates{10}, clusters.Coordinates{11}, clusters.Coordinates{12}, clusters.Coordinates{13},
+	}
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0}},
+		{Center: clusters.Coordinates{2}},
+	}
+	assignment := make([]int, len(d))
+
+	// driving the algorithm manually until no data point shifts anymore
+	km := New()
+	var steps int
+	for ; steps < 10; steps++ {
+		changed, err := km.Step(cc, d, assignment)
+		if err != nil {
+			t.Errorf("Unexpected error stepping: %v", err)
+			return
+		}
+		if steps == 0 && changed != 6 {
+			t.Errorf("Expected 6 data points to change clusters in the first step, got %d", changed)
+		}
+		if changed == 0 {
+			break
+		}
+	}
+	if cc[0].Center[0] != 1.5 || cc[1].Center[0] != 11.5 {
+		t.Errorf("Expected centers [1.5] and [11.5] after %d steps, got %v and %v", steps, cc[0].Center, cc[1].Center)
+	}
+	if !reflect.DeepEqual(assignment, []int{0, 0, 0, 0, 1, 1, 1, 1}) {
+		t.Errorf("Expected assignment [0 0 0 0 1 1 1 1], got %v", assignment)
+	}
+
+	if _, err := km.Step(cc, d, assignment[1:]); err == nil {
+		t.Errorf("Expected error stepping with a mismatching assignment, got nil")
+	}
+	if _, err := km.Step(cc, d, []int{0, 0, 0, 0, 0, 0, 0, 2}); err == nil {
+		t.Errorf("Expected error stepping with out of bounds clusters, got nil")
+	}
+}
+
+func TestShuffleEachIteration(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 256; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	partition := func(shuffle bool) clusters.Clusters {
+		km := New()
+		km.Init = InitForgy
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.ShuffleEachIteration = shuffle
+		cc, err := km.Partition(d, 4)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc
+	}
+
+	c1, c2 := partition(true), partition(true)
+	if !reflect.DeepEqual(c1, c2) {
+		t.Errorf("Expected identical clusters for the same seed")
+	}
+
+	// the members of the clusters no longer follow the order of the dataset
+	shuffled := false
+	for _, c := range c1 {
+		for i := 1; i < len(c.Observations); i++ {
+			if indexOf(d, c.Observations[i]) < indexOf(d, c.Observations[i-1]) {
+				shuffled = true
+			}
+		}
+	}
+	if !shuffled {
+		t.Errorf("Expected the members to be appended in a shuffled order")
+	}
+}
+
+// indexOf returns the index of the observation in the dataset, or -1
+func indexOf(dataset clusters.Observations, o clusters.Observation) int {
+	for i := range dataset {
+		if reflect.DeepEqual(dataset[i], o) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRecenter(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{5, 5},
+		clusters.Coordinates{5, 7},
+	}
+
+	km := New()
+	cc, err := km.Recenter(d, []int{0, 0, 1, 1}, 2)
+	if err != nil {
+		t.Errorf("Unexpected error recentering: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(cc[0].Center, clusters.Coordinates{1, 0}) ||
+		!reflect.DeepEqual(cc[1].Center, clusters.Coordinates{5, 6}) {
+		t.Errorf("Expected centers [1 0] and [5 6], got %v and %v", cc[0].Center, cc[1].Center)
+	}
+
+	a := []int{0, 0, 0, 0}
+	cc, err = km.Recenter(d, a, 2)
+	if err != nil {
+		t.Errorf("Unexpected error recentering: %v", err)
+		return
+	}
+	if len(cc[1].Observations) != 1 || a[0]+a[1]+a[2]+a[3] != 1 {
+		t.Errorf("Expected the empty cluster to be refilled with one data point, got assignment %v", a)
+	}
+
+	if _, err := km.Recenter(d, []int{0, 0, 1}, 2); err == nil {
+		t.Errorf("Expected error recentering with a mismatching assignment, got nil")
+	}
+	if _, err := km.Recenter(d, []int{0, 0, 1, 2}, 2); err == nil {
+		t.Errorf("Expected error recentering with an out of bounds assignment, got nil")
+	}
+}
+
+func TestInertia(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{5, 5},
+	}
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{1, 0}},
+		{Center: clusters.Coordinates{5, 4}},
+	}
+
+	km := New()
+	if in := km.inertia(d, []int{0, 0, 1}, cc); in != 3 {
+		t.Errorf("Expected inertia of 3, got %f", in)
+	}
+	km.Weights = []float64{1, 2, 0.5}
+	if in := km.inertia(d, []int{0, 0, 1}, cc); in != 3.5 {
+		t.Errorf("Expected weighted inertia of 3.5, got %f", in)
+	}
+}
+
+func TestInertiaThreads(t *testing.T) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 10000; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64() * 1e6,
+			rand.Float64() * 1e-6,
+		})
+	}
+
+	cc := make(clusters.Clusters, 8)
+	for ci := range cc {
+		cc[ci].Center = clusters.Coordinates{rand.Float64() * 1e6, rand.Float64() * 1e-6}
+	}
+	km := New()
+	a := km.PredictAll(cc, d)
+
+	single := km.inertia(d, a, cc)
+	for _, threads := range []int{2, 3, 8, 64} {
+		km.Threads = threads
+		if in := km.inertia(d, a, cc); in != single {
+			t.Errorf("Expected identical inertia %v with %d threads, got %v", single, threads, in)
+		}
+	}
+}
+
+func TestWeights(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{1, 0},
+		clusters.Coordinates{10, 10},
+		clusters.Coordinates{10, 11},
+	}
+	w := []float64{3, 1, 1, 1}
+
+	km := New()
+	km.Weights = w
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	a := km.PredictAll(cc, d)
+	ci := a[0]
+	if cc[ci].Center[0] != 0.25 || cc[ci].Center[1] != 0 {
+		t.Errorf("Expected center at the weighted mean [0.25 0], got %v", cc[ci].Center)
+	}
+
+	// the weighted means minimize the weighted objective, the unweighted
+	// means do not
+	unweighted := clusters.Clusters{
+		{Center: clusters.Coordinates{0.5, 0}},
+		{Center: clusters.Coordinates{10, 10.5}},
+	}
+	if ci != 0 {
+		unweighted[0], unweighted[1] = unweighted[1], unweighted[0]
+	}
+	if wi, ui := km.inertia(d, a, cc), km.inertia(d, a, unweighted); wi >= ui {
+		t.Errorf("Expected weighted inertia %f to be lower than %f", wi, ui)
+	}
+
+	km.Weights = w[1:]
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with mismatching weights, got nil")
+	}
+	km.Weights = []float64{1, 1, -1, 1}
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with negative weights, got nil")
+	}
+}
+
+func benchmarkPartition(size, partitions int, b *testing.B) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+
+	for i := 0; i < size; i++ {
+		d = append(d, clusters.Coordinates{
+			rand.Float64(),
+			rand.Float64(),
+		})
+	}
+
+	for j := 0; j < b.N; j++ {
+		km := New()
+		km.Partition(d, partitions)
+	}
+}
+
+func BenchmarkPartition32Points(b *testing.B)    { benchmarkPartition(32, 16, b) }
+func BenchmarkPartition512Points(b *testing.B)   { benchmarkPartition(512, 16, b) }
+func BenchmarkPartition4096Points(b *testing.B)  { benchmarkPartition(4096, 16, b) }
+func BenchmarkPartition65536Points(b *testing.B) { benchmarkPartition(65536, 16, b) }
+
+func benchmarkPartitionReusing(reuse bool, b *testing.B) {
+	d, _ := MakeBlobs(4096, 16, 2, 1, rand.New(rand.NewSource(randomSeed)))
+	km := New()
+	km.Init = InitKMeansPlusPlus
+	// a worker pool keeps the goroutines from dominating the allocations
+	km.Threads = 4
+
+	var cc clusters.Clusters
+	b.ReportAllocs()
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		if reuse {
+			cc, _ = km.PartitionReusing(cc, d, 16)
+		} else {
+			cc, _ = km.Partition(d, 16)
+		}
+	}
+}
+
+func BenchmarkPartitionFresh(b *testing.B)   { benchmarkPartitionReusing(false, b) }
+func BenchmarkPartitionReusing(b *testing.B) { benchmarkPartitionReusing(true, b) }
+
+// skewedClusters returns 16 clusters, the first of which holds 90% of the
+// data points
+func skewedClusters(size int) (clusters.Observations, clusters.Clusters, []int) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	cc := make(clusters.Clusters, 16)
+	a := make([]int, size)
+	for i := 0; i < size; i++ {
+		o := clusters.Coordinates{rand.Float64(), rand.Float64()}
+		d = append(d, o)
+		if i%10 != 0 {
+			a[i] = 0
+		} else {
+			a[i] = 1 + rand.Intn(15)
+		}
+		cc[a[i]].Append(o)
+	}
+	for ci := range cc {
+		cc[ci].Center = clusters.Coordinates{0, 0}
+	}
+	return d, cc, a
+}
+
+func TestRecenterSkewed(t *testing.T) {
+	d, cc, a := skewedClusters(1 << 14)
+	exp := make(clusters.Clusters, len(cc))
+	copy(exp, cc)
+	exp.Recenter()
+
+	km := New()
+	km.Threads = 4
+	km.recenter(cc, d, a)
+	for ci := range cc {
+		for j := range cc[ci].Center {
+			if math.Abs(cc[ci].Center[j]-exp[ci].Center[j]) > 1e-12 {
+				t.Errorf("Expected center %v, got %v", exp[ci].Center, cc[ci].Center)
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkRecenterSkewedPerCluster(b *testing.B) {
+	_, cc, _ := skewedClusters(1 << 18)
+	for j := 0; j < b.N; j++ {
+		cc.RecenterThreads(8)
+	}
+}
+
+func BenchmarkRecenterSkewedBalanced(b *testing.B) {
+	d, cc, a := skewedClusters(1 << 18)
+	km := New()
+	km.Threads = 8
+	for j := 0; j < b.N; j++ {
+		km.recenter(cc, d, a)
+	}
+}
+
+// alternating is an initializer seeding its centers in turn
+type alternating struct {
+	seeds [][]clusters.Coordinates
+	calls *int
+}
+
+func (a alternating) Init(dataset clusters.Observations, k int, rng *rand.Rand) ([]clusters.Coordinates, error) {
+	(*a.calls)++
+	return a.seeds[(*a.calls-1)%len(a.seeds)], nil
+}
+
+func TestTieTolerance(t *testing.T) {
+	// the horizontal split beats the vertical one by a relative 5e-10 only
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{0, 2},
+		clusters.Coordinates{2, 2 + 1e-9},
+	}
+	vertical := []clusters.Coordinates{{0, 1}, {2, 1}}
+	horizontal := []clusters.Coordinates{{1, 0}, {1, 2}}
+
+	fit := func(tol float64, seeds ...[]clusters.Coordinates) clusters.Coordinates {
+		km := New()
+		km.Init = alternating{seeds: seeds, calls: new(int)}
+		km.NInit = 4
+		km.TieTolerance = tol
+		cc, err := km.Partition(d, 2)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc[0].Center
+	}
+
+	if c := fit(0, vertical, horizontal); c[0] != 0 {
+		t.Errorf("Expected the first of the tied runs, got center %v", c)
+	}
+	if c := fit(0, horizontal, vertical); c[1] != 0 {
+		t.Errorf("Expected the first of the tied runs, got center %v", c)
+	}
+	if c := fit(-1, vertical, horizontal); c[1] != 0 {
+		t.Errorf("Expected the run with the lowest inertia, got center %v", c)
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	d, _ := MakeBlobs(1024, 4, 2, 1, rand.New(rand.NewSource(randomSeed)))
+
+	seeds := []clusters.Coordinates{{0, 0}, {1, 0}, {0, 1}, {1, 1}}
+	km := New()
+	km.Init = anchors{{0, 0}, {1, 0}, {0, 1}, {1, 1}}
+	km.RecordHistory = true
+	km.Polish = true
+	res, err := km.Fit(d, 4)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	history := res.Stats.History
+	if len(history) != res.Iterations+1 {
+		t.Errorf("Expected the seeds and the centers of %d iterations, got %d", res.Iterations, len(history))
+		return
+	}
+	if !reflect.DeepEqual(history[0], seeds) {
+		t.Errorf("Expected the seeds %v first, got %v", seeds, history[0])
+	}
+	for ci, c := range res.Clusters {
+		if !reflect.DeepEqual(history[len(history)-1][ci], c.Center) {
+			t.Errorf("Expected the final center %v last, got %v", c.Center, history[len(history)-1][ci])
+		}
+	}
+	if reflect.DeepEqual(history[1], history[0]) {
+		t.Errorf("Expected the centers to move in the first iteration")
+	}
+
+	km.RecordHistory = false
+	if res, _ := km.Fit(d, 4); res.Stats.History != nil {
+		t.Errorf("Expected no history without RecordHistory, got %d", len(res.Stats.History))
+	}
+}
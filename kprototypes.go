@@ -0,0 +1,114 @@
+package kmeans
+
+import (
+	"fmt"
+
+	"github.com/k----n/clusters"
+)
+
+// CenterMethod selects how the cluster centers are computed from their
+// members
+type CenterMethod int
+
+const (
+	// CenterMean places each center at the (weighted) mean of its members
+	CenterMean CenterMethod = iota
+	// CenterPrototype places each center at the (weighted) mean of its
+	// members in numeric dimensions, and at the (weighted) mode in the
+	// CategoricalFeatures, which is the center of k-prototypes. Ties
+	// between modes go to the smallest category code. Pair it with the
+	// KPrototypes distance
+	// See: https://doi.org/10.1023/A:1009769707641
+	CenterPrototype
+)
+
+// KPrototypes returns the k-prototypes cost between two points: the squared
+// Euclidean distance across the numeric dimensions plus gamma times the
+// number of mismatching categorical dimensions. gamma balances the
+// categorical against the numeric features; a common choice is the average
+// standard deviation of the numeric features
+func KPrototypes(categorical []int, gamma float64) DistanceFunc {
+	isCategorical := make(map[int]bool, len(categorical))
+	for _, j := range categorical {
+		isCategorical[j] = true
+	}
+
+	return func(a, b clusters.Coordinates) float64 {
+		var d float64
+		for j := range a {
+			switch {
+			case isCategorical[j]:
+				if a[j] != b[j] {
+					d += gamma
+				}
+			default:
+				d += (a[j] - b[j]) * (a[j] - b[j])
+			}
+		}
+		return d
+	}
+}
+
+// checkCenter validates the Center, Refill and CategoricalFeatures against
+// the dimensions of the dataset
+func (m Kmeans) checkCenter(dataset clusters.Observations) error {
+	if m.Center != CenterMean && m.Center != CenterPrototype {
+		return fmt.Errorf("unknown center method %d", m.Center)
+	}
+	if m.Aggregator != nil && m.Center != CenterMean {
+		return fmt.Errorf("an aggregator can't be combined with center method %d", m.Center)
+	}
+	if m.Refill != RefillRandom && m.Refill != RefillLargest && m.Refill != RefillHighestSSE {
+		return fmt.Errorf("unknown refill method %d", m.Refill)
+	}
+	if len(dataset) == 0 {
+		return nil
+	}
+	for _, j := range m.CategoricalFeatures {
+		if j < 0 || j >= len(dataset[0].Coordinates()) {
+			return fmt.Errorf("categorical feature %d is out of bounds (must be between 0 and %d)", j, len(dataset[0].Coordinates())-1)
+		}
+	}
+	return nil
+}
+
+// recenterModes replaces the categorical dimensions of each center by the
+// (weighted) mode of its members. members holds the indices of each
+// cluster's members, which are required if weights are configured,
+// otherwise nil takes the member lists. Empty and frozen clusters keep
+// their center
+func (m Kmeans) recenterModes(cc clusters.Clusters, dataset clusters.Observations, members [][]int, frozen []bool) {
+	m.forEach(len(cc), func(ci int) {
+		if frozen != nil && frozen[ci] {
+			return
+		}
+
+		for _, j := range m.CategoricalFeatures {
+			counts := make(map[float64]float64)
+			mode, best := 0.0, 0.0
+			count := func(v, w float64) {
+				counts[v] += w
+				if c := counts[v]; c > best || (c == best && v < mode) {
+					mode, best = v, c
+				}
+			}
+			if members != nil {
+				for _, i := range members[ci] {
+					w := 1.0
+					if m.Weights != nil {
+						w = m.Weights[i]
+					}
+					count(dataset[i].Coordinates()[j], w)
+				}
+			} else {
+				for _, o := range cc[ci].Observations {
+					count(o.Coordinates()[j], 1)
+				}
+			}
+
+			if best > 0 {
+				cc[ci].Center[j] = mode
+			}
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package kmeans
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestKPrototypes(t *testing.T) {
+	cost := KPrototypes([]int{1}, 0.5)
+	if d := cost(clusters.Coordinates{0, 3}, clusters.Coordinates{2, 7}); d != 4.5 {
+		t.Errorf("Expected cost 4.5, got %f", d)
+	}
+
+	// two numeric groups, each with a dominant category
+	var d clusters.Observations
+	for i := 0; i < 64; i++ {
+		x, c := float64(i%8)/8.0, 2.0
+		if i%4 == 0 {
+			c = 5
+		}
+		if i >= 32 {
+			x, c = x+10, 7-c
+		}
+		d = append(d, clusters.Coordinates{x, c})
+	}
+
+	km := New()
+	km.Init = InitKMeansPlusPlus
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Center = CenterPrototype
+	km.CategoricalFeatures = []int{1}
+	km.Distance = KPrototypes(km.CategoricalFeatures, 0.5)
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	for _, c := range cc {
+		want := 2.0
+		if c.Center[0] > 5 {
+			want = 5
+		}
+		if c.Center[1] != want {
+			t.Errorf("Expected the mode %f as categorical center, got %v", want, c.Center)
+		}
+	}
+
+	km.CategoricalFeatures = []int{2}
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with an out of bounds categorical feature, got nil")
+	}
+	km.Center = CenterMethod(-1)
+	if _, err := km.Partition(d, 2); err == nil {
+		t.Errorf("Expected error partitioning with an unknown center method, got nil")
+	}
+}
@@ -0,0 +1,36 @@
+package kmeans
+
+import (
+	"github.com/k----n/clusters"
+)
+
+// lean returns whether the runs keep no member lists while fitting, see
+// LeanMemory
+func (m Kmeans) lean() bool {
+	return m.LeanMemory && m.Aggregator == nil && m.BalancePenalty == 0 && m.Center != CenterPrototype
+}
+
+// memberCounts returns the number of data points assigned to each of the k
+// clusters
+func memberCounts(k int, assignment []int) []int {
+	counts := make([]int, k)
+	for _, ci := range assignment {
+		counts[ci]++
+	}
+	return counts
+}
+
+// fillMembers fills the member lists of the clusters from the assignment,
+// in the order of the dataset, reusing their storage
+func fillMembers(cc clusters.Clusters, dataset clusters.Observations, assignment []int) {
+	counts := memberCounts(len(cc), assignment)
+	for ci := range cc {
+		if cap(cc[ci].Observations) < counts[ci] {
+			cc[ci].Observations = make(clusters.Observations, 0, counts[ci])
+		}
+		cc[ci].Observations = cc[ci].Observations[:0]
+	}
+	for i, ci := range assignment {
+		cc[ci].Observations = append(cc[ci].Observations, dataset[i])
+	}
+}
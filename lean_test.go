@@ -0,0 +1,79 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestLeanMemory(t *testing.T) {
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	weights := make([]float64, 2048)
+	for i := range weights {
+		// away from the unit hypercube, so random seeds end up empty
+		d = append(d, clusters.Coordinates{2 + rng.Float64()*4, 2 + rng.Float64()*4, 2 + rng.Float64()*4})
+		weights[i] = rng.Float64()
+	}
+
+	partition := func(lean bool, init InitMethod, refill RefillMethod, threads int, weights []float64) (clusters.Clusters, []int) {
+		km := New()
+		km.Init = init
+		km.Refill = refill
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.LeanMemory = lean
+		km.Threads = threads
+		km.Weights = weights
+		cc := clusters.Clusters{}
+		assignment := make([]int, len(d))
+		if err := km.PartitionInto(&cc, assignment, d, 8); err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc, assignment
+	}
+
+	for _, init := range []InitMethod{InitKMeansPlusPlus, InitRandom} {
+		for _, refill := range []RefillMethod{RefillRandom, RefillLargest} {
+			for _, w := range [][]float64{nil, weights} {
+				for _, threads := range []int{1, 4} {
+					exp, expAssignment := partition(false, init, refill, threads, w)
+					cc, assignment := partition(true, init, refill, threads, w)
+					if !reflect.DeepEqual(assignment, expAssignment) {
+						t.Errorf("Expected the assignment of a default fit with init %d, refill %d and %d threads", init, refill, threads)
+						continue
+					}
+					for ci := range exp {
+						// the member lists of a default fit are in the order the
+						// threads assigned them in
+						if !reflect.DeepEqual(cc[ci].Center, exp[ci].Center) || len(cc[ci].Observations) != len(exp[ci].Observations) {
+							t.Errorf("Expected the clusters of a default fit with init %d, refill %d and %d threads", init, refill, threads)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func benchmarkPartitionLean(lean bool, b *testing.B) {
+	d, _ := MakeBlobs(16384, 16, 2, 1, rand.New(rand.NewSource(randomSeed)))
+
+	km := New()
+	km.Threads = 4
+	km.FusedRecenter = !lean
+	km.LeanMemory = lean
+	b.ReportAllocs()
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		if _, err := km.Partition(d, 16); err != nil {
+			b.Fatalf("Unexpected error partitioning: %v", err)
+		}
+	}
+}
+
+func BenchmarkPartitionFused(b *testing.B) { benchmarkPartitionLean(false, b) }
+func BenchmarkPartitionLean(b *testing.B)  { benchmarkPartitionLean(true, b) }
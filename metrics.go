@@ -0,0 +1,279 @@
+package kmeans
+
+import (
+	"math"
+)
+
+// AdjustedRandIndex returns the adjusted Rand index between two assignments
+// of the same data points, e.g. the cluster indices of two runs with
+// different seeds. It is 1.0 for identical partitions (regardless of the
+// label values), close to 0.0 for independent ones, and can be negative for
+// partitions which agree less than expected by chance. Degenerate cases
+// where both assignments put all points into a single cluster, or each
+// point into its own cluster, are treated as identical partitions (1.0).
+// If the assignments differ in length, NaN is returned
+// See: https://en.wikipedia.org/wiki/Rand_index#Adjusted_Rand_index
+func AdjustedRandIndex(a, b []int) float64 {
+	if len(a) != len(b) {
+		return math.NaN()
+	}
+
+	index, sumA, sumB, pairs := pairCounts(a, b)
+	if pairs == 0 {
+		return 1.0
+	}
+
+	expected := sumA * sumB / pairs
+	maxIndex := (sumA + sumB) / 2
+	if maxIndex == expected {
+		return 1.0
+	}
+	return (index - expected) / (maxIndex - expected)
+}
+
+// RandIndex returns the (unadjusted) Rand index between two assignments of
+// the same data points: the fraction of all pairs of data points on which
+// they agree, either by putting both data points into the same cluster or
+// by separating them. It is 1.0 for identical partitions (regardless of the
+// label values), but unlike the AdjustedRandIndex, independent partitions
+// can score well above 0.0, especially for many clusters. With fewer than
+// two data points there are no pairs to disagree on, so the assignments
+// are treated as identical partitions (1.0). If the assignments differ in
+// length, NaN is returned
+// See: https://en.wikipedia.org/wiki/Rand_index
+func RandIndex(a, b []int) float64 {
+	if len(a) != len(b) {
+		return math.NaN()
+	}
+
+	both, inA, inB, pairs := pairCounts(a, b)
+	if pairs == 0 {
+		return 1.0
+	}
+	// pairs apart in both assignments are all pairs but the ones together
+	// in either of them
+	apart := pairs - inA - inB + both
+	return (both + apart) / pairs
+}
+
+// NMI returns the normalized mutual information between two assignments of
+// the same data points, using the arithmetic mean of both entropies for
+// normalization. It is 1.0 for identical partitions (regardless of the label
+// values) and 0.0 for independent ones. Empty assignments and assignments
+// which both put all points into a single cluster are treated as identical
+// partitions (1.0), while a single cluster compared to any finer partition
+// shares no information with it (0.0). If the assignments differ in length,
+// NaN is returned
+// See: https://en.wikipedia.org/wiki/Mutual_information#Normalized_variants
+func NMI(a, b []int) float64 {
+	if len(a) != len(b) {
+		return math.NaN()
+	}
+
+	cells, rows, cols := contingency(a, b)
+	ha, hb := entropy(rows, len(a)), entropy(cols, len(b))
+	if ha == 0 && hb == 0 {
+		return 1.0
+	}
+
+	n := float64(len(a))
+	var mi float64
+	for c, nij := range cells {
+		p := float64(nij) / n
+		mi += p * math.Log(float64(nij)*n/(float64(rows[c.a])*float64(cols[c.b])))
+	}
+
+	nmi := mi / ((ha + hb) / 2)
+	// rounding errors can push the ratio of two identical partitions past 1
+	return math.Min(math.Max(nmi, 0), 1)
+}
+
+// ContingencyMatrix cross-tabulates predicted clusters against true classes:
+// entry [i][j] counts the data points assigned to cluster i which belong to
+// class j. The matrix has max(pred)+1 rows and max(truth)+1 columns. If the
+// assignments differ in length or contain negative labels, nil is returned
+func ContingencyMatrix(pred, truth []int) [][]int {
+	if len(pred) != len(truth) {
+		return nil
+	}
+
+	rows, cols := 0, 0
+	for i := range pred {
+		if pred[i] < 0 || truth[i] < 0 {
+			return nil
+		}
+		if pred[i] >= rows {
+			rows = pred[i] + 1
+		}
+		if truth[i] >= cols {
+			cols = truth[i] + 1
+		}
+	}
+
+	m := make([][]int, rows)
+	for i := range m {
+		m[i] = make([]int, cols)
+	}
+	for i := range pred {
+		m[pred[i]][truth[i]]++
+	}
+	return m
+}
+
+// BestMatch matches predicted clusters with true classes one to one so
+// they agree on as many data points as possible, solving the assignment
+// problem on the ContingencyMatrix with the Hungarian algorithm. It returns
+// the class of each cluster, and the clustering accuracy: the fraction of
+// data points whose cluster maps to their class. With more clusters than
+// classes, the clusters left over are missing from the mapping, and their
+// data points count as misclassified; with more classes than clusters, the
+// classes left over have no cluster. Only labels which occur get matched.
+// Empty assignments are treated as identical partitions (1.0). If the
+// assignments differ in length or contain negative labels, nil and NaN are
+// returned
+// See: https://en.wikipedia.org/wiki/Hungarian_algorithm
+func BestMatch(pred, truth []int) (mapping map[int]int, accuracy float64) {
+	if len(pred) == 0 && len(truth) == 0 {
+		return map[int]int{}, 1
+	}
+	cm := ContingencyMatrix(pred, truth)
+	if cm == nil {
+		return nil, math.NaN()
+	}
+
+	n := len(cm)
+	if len(cm[0]) > n {
+		n = len(cm[0])
+	}
+	cost := make([][]int, n)
+	for i := range cost {
+		cost[i] = make([]int, n)
+		if i < len(cm) {
+			for j, c := range cm[i] {
+				cost[i][j] = -c
+			}
+		}
+	}
+
+	_, rows, cols := contingency(pred, truth)
+	mapping = make(map[int]int)
+	matched := 0
+	for i, j := range hungarian(cost) {
+		if _, ok := rows[i]; !ok {
+			continue
+		}
+		if _, ok := cols[j]; !ok {
+			continue
+		}
+		mapping[i] = j
+		matched += cm[i][j]
+	}
+	return mapping, float64(matched) / float64(len(pred))
+}
+
+// hungarian returns the column assigned to each row of the square cost
+// matrix by an assignment of minimum total cost, in O(n³)
+func hungarian(cost [][]int) []int {
+	const inf = int(^uint(0) >> 1)
+	n := len(cost)
+	// potentials of the rows and columns, and the row matched to each
+	// column, 1-based with column 0 as the sentinel
+	u := make([]int, n+1)
+	v := make([]int, n+1)
+	match := make([]int, n+1)
+	way := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		match[0] = i
+		j0 := 0
+		minv := make([]int, n+1)
+		used := make([]bool, n+1)
+		for j := range minv {
+			minv[j] = inf
+		}
+		for match[j0] != 0 {
+			used[j0] = true
+			i0, delta, j1 := match[j0], inf, 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := cost[i0-1][j-1] - u[i0] - v[j]; cur < minv[j] {
+					minv[j], way[j] = cur, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			match[j0] = match[j1]
+			j0 = j1
+		}
+	}
+
+	assigned := make([]int, n)
+	for j := 1; j <= n; j++ {
+		assigned[match[j]-1] = j - 1
+	}
+	return assigned
+}
+
+// cell is an entry of the contingency table between two assignments
+type cell struct {
+	a, b int
+}
+
+// contingency returns the sparse contingency table of two assignments along
+// with the cluster sizes of each assignment
+func contingency(a, b []int) (cells map[cell]int, rows, cols map[int]int) {
+	cells = make(map[cell]int)
+	rows = make(map[int]int)
+	cols = make(map[int]int)
+	for i := range a {
+		cells[cell{a[i], b[i]}]++
+		rows[a[i]]++
+		cols[b[i]]++
+	}
+	return cells, rows, cols
+}
+
+// entropy returns the entropy of an assignment given its cluster sizes
+func entropy(sizes map[int]int, n int) float64 {
+	var h float64
+	for _, s := range sizes {
+		p := float64(s) / float64(n)
+		h -= p * math.Log(p)
+	}
+	return h
+}
+
+// pairCounts returns the number of point pairs which are grouped together
+// in both assignments, in a, in b and the total number of pairs
+func pairCounts(a, b []int) (both, inA, inB, pairs float64) {
+	cells, rows, cols := contingency(a, b)
+	for _, n := range cells {
+		both += choose2(n)
+	}
+	for _, n := range rows {
+		inA += choose2(n)
+	}
+	for _, n := range cols {
+		inB += choose2(n)
+	}
+	return both, inA, inB, choose2(len(a))
+}
+
+// choose2 returns the number of unordered pairs among n elements
+func choose2(n int) float64 {
+	return float64(n) * float64(n-1) / 2
+}
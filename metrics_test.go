@@ -0,0 +1,137 @@
+package kmeans
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestAdjustedRandIndex(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		ari  float64
+	}{
+		{[]int{0, 0, 1, 1}, []int{0, 0, 1, 1}, 1.0},
+		{[]int{0, 0, 1, 1}, []int{1, 1, 0, 0}, 1.0},
+		{[]int{0, 0, 1, 1}, []int{0, 0, 1, 2}, 0.5714285714285715},
+		{[]int{0, 0, 0, 0}, []int{0, 1, 2, 3}, 0.0},
+		{[]int{0, 0, 0, 0}, []int{5, 5, 5, 5}, 1.0},
+		{[]int{0, 1, 2, 3}, []int{3, 2, 1, 0}, 1.0},
+		{[]int{0}, []int{1}, 1.0},
+	}
+
+	for _, tt := range tests {
+		if ari := AdjustedRandIndex(tt.a, tt.b); math.Abs(ari-tt.ari) > 1e-12 {
+			t.Errorf("Expected ARI of %v and %v to be %f, got %f", tt.a, tt.b, tt.ari, ari)
+		}
+	}
+
+	if ari := AdjustedRandIndex([]int{0, 1}, []int{0}); !math.IsNaN(ari) {
+		t.Errorf("Expected NaN for assignments of different length, got %f", ari)
+	}
+}
+
+func TestRandIndex(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		ri   float64
+	}{
+		{[]int{0, 0, 1, 1}, []int{1, 1, 0, 0}, 1.0},
+		// disagreeing on the pair (2, 3) only
+		{[]int{0, 0, 1, 1}, []int{0, 0, 1, 2}, 5.0 / 6},
+		{[]int{0, 0, 1, 1}, []int{0, 1, 0, 1}, 2.0 / 6},
+		{[]int{0, 0, 0, 0}, []int{1, 1, 1, 1}, 1.0},
+		{[]int{0, 0, 0, 0}, []int{0, 1, 2, 3}, 0.0},
+		{[]int{0}, []int{1}, 1.0},
+		{[]int{}, []int{}, 1.0},
+	}
+
+	for _, tt := range tests {
+		if ri := RandIndex(tt.a, tt.b); math.Abs(ri-tt.ri) > 1e-12 {
+			t.Errorf("Expected Rand index of %v and %v to be %f, got %f", tt.a, tt.b, tt.ri, ri)
+		}
+	}
+
+	if ri := RandIndex([]int{0, 1}, []int{0}); !math.IsNaN(ri) {
+		t.Errorf("Expected NaN for assignments of different length, got %f", ri)
+	}
+}
+
+func TestNMI(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		nmi  float64
+	}{
+		{[]int{0, 0, 1, 1}, []int{1, 1, 0, 0}, 1.0},
+		{[]int{0, 0, 1, 1}, []int{0, 0, 1, 2}, 0.8},
+		{[]int{0, 0, 1, 1}, []int{0, 1, 0, 1}, 0.0},
+		{[]int{0, 0, 0, 0}, []int{1, 1, 1, 1}, 1.0},
+		{[]int{0, 0, 0, 0}, []int{0, 1, 2, 3}, 0.0},
+		{[]int{}, []int{}, 1.0},
+	}
+
+	for _, tt := range tests {
+		if nmi := NMI(tt.a, tt.b); math.Abs(nmi-tt.nmi) > 1e-12 {
+			t.Errorf("Expected NMI of %v and %v to be %f, got %f", tt.a, tt.b, tt.nmi, nmi)
+		}
+	}
+
+	if nmi := NMI([]int{0, 1}, []int{0}); !math.IsNaN(nmi) {
+		t.Errorf("Expected NaN for assignments of different length, got %f", nmi)
+	}
+}
+
+func TestContingencyMatrix(t *testing.T) {
+	m := ContingencyMatrix([]int{0, 0, 1, 2, 2, 2}, []int{1, 1, 0, 0, 1, 0})
+	exp := [][]int{
+		{0, 2},
+		{1, 0},
+		{2, 1},
+	}
+	if !reflect.DeepEqual(m, exp) {
+		t.Errorf("Expected contingency matrix %v, got %v", exp, m)
+	}
+
+	if m := ContingencyMatrix([]int{0, 1}, []int{0}); m != nil {
+		t.Errorf("Expected nil for assignments of different length, got %v", m)
+	}
+	if m := ContingencyMatrix([]int{0, -1}, []int{0, 0}); m != nil {
+		t.Errorf("Expected nil for negative labels, got %v", m)
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	// cluster 0 ties between classes 0 and 1, but matching it with class 1
+	// leaves cluster 1 without a shared class, agreeing on 2+0+1 data
+	// points instead of 2+2+1
+	pred := []int{0, 0, 0, 0, 1, 1, 2, 2}
+	truth := []int{1, 1, 0, 0, 1, 1, 0, 2}
+	mapping, accuracy := BestMatch(pred, truth)
+	if exp := map[int]int{0: 0, 1: 1, 2: 2}; !reflect.DeepEqual(mapping, exp) {
+		t.Errorf("Expected mapping %v, got %v", exp, mapping)
+	}
+	if accuracy != 5.0/8 {
+		t.Errorf("Expected accuracy %f, got %f", 5.0/8, accuracy)
+	}
+
+	// permuted labels match perfectly
+	mapping, accuracy = BestMatch([]int{2, 2, 0, 1, 1}, []int{0, 0, 1, 2, 2})
+	if exp := map[int]int{2: 0, 0: 1, 1: 2}; !reflect.DeepEqual(mapping, exp) || accuracy != 1 {
+		t.Errorf("Expected mapping %v with accuracy 1, got %v with %f", exp, mapping, accuracy)
+	}
+
+	// more clusters than classes, and the other way round
+	if mapping, accuracy := BestMatch([]int{0, 0, 1, 2}, []int{0, 0, 1, 1}); len(mapping) != 2 || mapping[0] != 0 || accuracy != 0.75 {
+		t.Errorf("Expected 2 matched clusters with accuracy 0.75, got %v with %f", mapping, accuracy)
+	}
+	if mapping, accuracy := BestMatch([]int{0, 0, 0, 1}, []int{0, 1, 1, 2}); !reflect.DeepEqual(mapping, map[int]int{0: 1, 1: 2}) || accuracy != 0.75 {
+		t.Errorf("Expected mapping map[0:1 1:2] with accuracy 0.75, got %v with %f", mapping, accuracy)
+	}
+
+	if mapping, accuracy := BestMatch(nil, nil); len(mapping) != 0 || accuracy != 1 {
+		t.Errorf("Expected accuracy 1 for empty assignments, got %v with %f", mapping, accuracy)
+	}
+	if mapping, accuracy := BestMatch([]int{0, 1}, []int{0}); mapping != nil || !math.IsNaN(accuracy) {
+		t.Errorf("Expected NaN for assignments of different length, got %v with %f", mapping, accuracy)
+	}
+}
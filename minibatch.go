@@ -0,0 +1,214 @@
+package kmeans
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/k----n/classifier/parallel"
+	"github.com/k----n/clusters"
+)
+
+// MiniBatch is a stateful mini-batch k-means clusterer. It refines its
+// centroids with every batch of observations it is fed, which allows
+// clustering a stream of data without ever holding all of it in memory.
+// Unlike Kmeans, a MiniBatch isn't safe for concurrent use
+// See: https://www.eecs.tufts.edu/~dsculley/papers/fastkmeans.pdf
+type MiniBatch struct {
+	// number of threads
+	Threads int
+	// Rand is the source of randomness used for seeding the centroids from
+	// the first batch. When nil, the global source is used
+	Rand *rand.Rand
+	// Decay (between 0.0 and 1.0) optionally makes the centroids forget
+	// old observations to track drifting data: before each update, the
+	// number of observations a centroid has absorbed gets multiplied by
+	// 1-Decay. The learning rate of a centroid then settles at Decay, so it
+	// effectively averages over its last 1/Decay observations, with
+	// exponentially decreasing weights. Zero disables the decay, which is
+	// standard sequential k-means
+	Decay float64
+
+	k int
+	// current centroids
+	centers []clusters.Coordinates
+	// (decayed) number of observations each centroid has absorbed so far,
+	// used for the per-centroid learning rate
+	counts []float64
+}
+
+// NewMiniBatch returns a mini-batch clusterer for k clusters. The centroids
+// get seeded from the first batch passed to PartialFit
+func NewMiniBatch(k int) (*MiniBatch, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be greater than 0")
+	}
+
+	return &MiniBatch{
+		k: k,
+	}, nil
+}
+
+// NewMiniBatchWithCentroids returns a mini-batch clusterer starting from the
+// given centroids, e.g. picked from a stream by a Reservoir
+func NewMiniBatchWithCentroids(centroids []clusters.Coordinates) (*MiniBatch, error) {
+	if len(centroids) == 0 {
+		return nil, fmt.Errorf("k must be greater than 0")
+	}
+
+	mb := &MiniBatch{
+		k:       len(centroids),
+		centers: make([]clusters.Coordinates, len(centroids)),
+		counts:  make([]float64, len(centroids)),
+	}
+	for i, c := range centroids {
+		if len(c) == 0 || len(c) != len(centroids[0]) {
+			return nil, fmt.Errorf("all centroids must have the same, non-zero number of dimensions")
+		}
+		mb.centers[i] = append(clusters.Coordinates{}, c...)
+	}
+	return mb, nil
+}
+
+// PartialFit updates the centroids with a batch of observations. The first
+// batch must contain at least k observations, k of which are randomly
+// picked as the initial centroids
+func (mb *MiniBatch) PartialFit(batch clusters.Observations) error {
+	if mb.Decay < 0 || mb.Decay > 1 || math.IsNaN(mb.Decay) {
+		return fmt.Errorf("the decay is out of bounds (must be between 0.0 and 1.0)")
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	if mb.centers == nil {
+		if err := mb.seed(batch); err != nil {
+			return err
+		}
+	}
+
+	// cache the nearest centroid of every observation in the batch before
+	// moving any of the centroids
+	nearest := make([]int, len(batch))
+	parallel.ForEach(len(batch), mb.Threads, func(i int) {
+		nearest[i] = mb.Predict(batch[i])
+	})
+
+	for i, o := range batch {
+		ci := nearest[i]
+		mb.counts[ci] = (1-mb.Decay)*mb.counts[ci] + 1
+
+		// per-centroid learning rate, decaying with the number of
+		// observations the centroid has absorbed
+		eta := 1.0 / mb.counts[ci]
+		for j, v := range o.Coordinates() {
+			mb.centers[ci][j] += eta * (v - mb.centers[ci][j])
+		}
+	}
+
+	return nil
+}
+
+// Centroids returns a copy of the current centroids
+func (mb *MiniBatch) Centroids() []clusters.Coordinates {
+	cc := make([]clusters.Coordinates, len(mb.centers))
+	for i, c := range mb.centers {
+		cc[i] = append(clusters.Coordinates{}, c...)
+	}
+	return cc
+}
+
+// Predict returns the index of the centroid nearest to the observation, or
+// -1 if the clusterer has not been fed any data yet
+func (mb *MiniBatch) Predict(o clusters.Observation) int {
+	ci := -1
+	dist := -1.0
+
+	for i, c := range mb.centers {
+		d := o.Distance(c)
+		if dist < 0 || d < dist {
+			dist = d
+			ci = i
+		}
+	}
+
+	return ci
+}
+
+// seed picks k distinct observations of the batch as initial centroids
+func (mb *MiniBatch) seed(batch clusters.Observations) error {
+	if len(batch) < mb.k {
+		return fmt.Errorf("the size of the first batch must at least equal k")
+	}
+	if len(batch[0].Coordinates()) == 0 {
+		return fmt.Errorf("there must be at least one dimension in the data set")
+	}
+
+	perm := rand.Perm //nolint:gosec // rand.Perm is good enough for this
+	if mb.Rand != nil {
+		perm = mb.Rand.Perm
+	}
+
+	mb.centers = make([]clusters.Coordinates, mb.k)
+	mb.counts = make([]float64, mb.k)
+	for i, p := range perm(len(batch))[:mb.k] {
+		mb.centers[i] = append(clusters.Coordinates{}, batch[p].Coordinates()...)
+	}
+	return nil
+}
+
+// Reservoir picks k observations uniformly at random from a stream of
+// unknown length in a single pass, without buffering the stream. The picked
+// observations are suitable initial centroids for a MiniBatch clusterer. A
+// Reservoir isn't safe for concurrent use
+// See: https://en.wikipedia.org/wiki/Reservoir_sampling
+type Reservoir struct {
+	k   int
+	rng *rand.Rand
+	// number of observations seen so far
+	n       int
+	samples []clusters.Coordinates
+}
+
+// NewReservoir returns a reservoir sampler for k observations, drawing all
+// random choices from rng. When rng is nil, a fresh source is used
+func NewReservoir(k int, rng *rand.Rand) (*Reservoir, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be greater than 0")
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(rand.Int63())) //nolint:gosec // math/rand is good enough for this
+	}
+
+	return &Reservoir{
+		k:   k,
+		rng: rng,
+	}, nil
+}
+
+// Add offers the next observation of the stream to the reservoir
+func (r *Reservoir) Add(o clusters.Observation) {
+	r.n++
+	if len(r.samples) < r.k {
+		r.samples = append(r.samples, append(clusters.Coordinates{}, o.Coordinates()...))
+		return
+	}
+
+	// keep the observation with probability k/n
+	if i := r.rng.Intn(r.n); i < r.k {
+		r.samples[i] = append(r.samples[i][:0], o.Coordinates()...)
+	}
+}
+
+// Centroids returns the k sampled observations. It fails if the stream
+// contained fewer than k observations so far
+func (r *Reservoir) Centroids() ([]clusters.Coordinates, error) {
+	if len(r.samples) < r.k {
+		return nil, fmt.Errorf("the stream must contain at least k observations")
+	}
+
+	cc := make([]clusters.Coordinates, r.k)
+	for i, c := range r.samples {
+		cc[i] = append(clusters.Coordinates{}, c...)
+	}
+	return cc, nil
+}
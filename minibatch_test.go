@@ -0,0 +1,145 @@
+package kmeans
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestMiniBatchPartialFit(t *testing.T) {
+	if _, err := NewMiniBatch(0); err == nil {
+		t.Errorf("Expected error creating a mini-batch clusterer with 0 clusters, got nil")
+	}
+
+	mb, err := NewMiniBatch(1)
+	if err != nil {
+		t.Errorf("Unexpected error creating mini-batch clusterer: %v", err)
+		return
+	}
+	if ci := mb.Predict(clusters.Coordinates{0, 0}); ci != -1 {
+		t.Errorf("Expected prediction -1 before fitting, got %d", ci)
+	}
+
+	// with a single centroid the per-centroid learning rate yields the
+	// running mean of all observations across batches
+	var sum float64
+	var n int
+	for b := 0; b < 4; b++ {
+		var batch clusters.Observations
+		for i := 0; i < 8; i++ {
+			v := float64(b*8+i) / 32.0
+			batch = append(batch, clusters.Coordinates{v, 1 - v})
+			sum += v
+			n++
+		}
+		if err := mb.PartialFit(batch); err != nil {
+			t.Errorf("Unexpected error fitting batch: %v", err)
+			return
+		}
+	}
+
+	c := mb.Centroids()
+	if len(c) != 1 {
+		t.Errorf("Expected 1 centroid, got %d", len(c))
+		return
+	}
+	if math.Abs(c[0][0]-sum/float64(n)) > 1e-9 || math.Abs(c[0][1]-(1-sum/float64(n))) > 1e-9 {
+		t.Errorf("Expected centroid at the mean [%f %f], got %v", sum/float64(n), 1-sum/float64(n), c[0])
+	}
+	if ci := mb.Predict(clusters.Coordinates{0, 0}); ci != 0 {
+		t.Errorf("Expected prediction 0, got %d", ci)
+	}
+
+	mb, _ = NewMiniBatch(4)
+	if err := mb.PartialFit(clusters.Observations{clusters.Coordinates{0, 0}}); err == nil {
+		t.Errorf("Expected error seeding from a batch smaller than k, got nil")
+	}
+}
+
+func TestMiniBatchDecay(t *testing.T) {
+	fit := func(decay float64) clusters.Coordinates {
+		mb, _ := NewMiniBatchWithCentroids([]clusters.Coordinates{{0}})
+		mb.Decay = decay
+		// the data drifts from 0 to 10
+		for b := 0; b < 10; b++ {
+			var batch clusters.Observations
+			for i := 0; i < 100; i++ {
+				batch = append(batch, clusters.Coordinates{float64(b)})
+			}
+			if err := mb.PartialFit(batch); err != nil {
+				t.Fatalf("Unexpected error fitting batch: %v", err)
+			}
+		}
+		return mb.Centroids()[0]
+	}
+
+	// without decay, the centroid is the mean of all observations
+	if c := fit(0); math.Abs(c[0]-4.5) > 1e-9 {
+		t.Errorf("Expected centroid at the mean 4.5, got %v", c)
+	}
+	// with a window of 10 observations, it tracks the latest batch
+	if c := fit(0.1); math.Abs(c[0]-9) > 1e-3 {
+		t.Errorf("Expected centroid close to the latest batch at 9, got %v", c)
+	}
+
+	mb, _ := NewMiniBatch(1)
+	mb.Decay = 1.5
+	if err := mb.PartialFit(clusters.Observations{clusters.Coordinates{0}}); err == nil {
+		t.Errorf("Expected error fitting with a decay above 1, got nil")
+	}
+}
+
+func TestReservoir(t *testing.T) {
+	if _, err := NewReservoir(0, nil); err == nil {
+		t.Errorf("Expected error creating a reservoir for 0 observations, got nil")
+	}
+
+	// every observation of the stream should be equally likely to be picked
+	counts := make([]int, 16)
+	rng := rand.New(rand.NewSource(randomSeed))
+	for run := 0; run < 4000; run++ {
+		r, _ := NewReservoir(4, rng)
+		if _, err := r.Centroids(); err == nil {
+			t.Errorf("Expected error retrieving centroids of an empty stream, got nil")
+			return
+		}
+		for i := range counts {
+			r.Add(clusters.Coordinates{float64(i)})
+		}
+
+		cc, err := r.Centroids()
+		if err != nil {
+			t.Errorf("Unexpected error retrieving centroids: %v", err)
+			return
+		}
+		for _, c := range cc {
+			counts[int(c[0])]++
+		}
+	}
+	for i, c := range counts {
+		// expected 1000 picks per observation
+		if c < 850 || c > 1150 {
+			t.Errorf("Expected observation %d to be picked about 1000 times, got %d", i, c)
+		}
+	}
+
+	r, _ := NewReservoir(2, rand.New(rand.NewSource(randomSeed)))
+	for i := 0; i < 8; i++ {
+		r.Add(clusters.Coordinates{float64(i), 0})
+	}
+	cc, _ := r.Centroids()
+	mb, err := NewMiniBatchWithCentroids(cc)
+	if err != nil {
+		t.Errorf("Unexpected error creating mini-batch clusterer: %v", err)
+		return
+	}
+	if c := mb.Centroids(); !reflect.DeepEqual(c, cc) {
+		t.Errorf("Expected centroids %v, got %v", cc, c)
+	}
+	if _, err := NewMiniBatchWithCentroids([]clusters.Coordinates{{0, 0}, {0}}); err == nil {
+		t.Errorf("Expected error creating a mini-batch clusterer with mismatching centroids, got nil")
+	}
+}
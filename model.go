@@ -0,0 +1,97 @@
+package kmeans
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/k----n/clusters"
+)
+
+// model is the JSON representation of a fitted clustering
+type model struct {
+	Centroids []clusters.Coordinates `json:"centroids"`
+}
+
+// SaveModel writes the centers of the clusters to w as JSON, in the form
+// {"centroids": [[x, y, ...], ...]}. The members of the clusters are not
+// saved
+func SaveModel(w io.Writer, cc clusters.Clusters) error {
+	mdl := model{
+		Centroids: make([]clusters.Coordinates, len(cc)),
+	}
+	for ci, c := range cc {
+		mdl.Centroids[ci] = c.Center
+	}
+
+	return json.NewEncoder(w).Encode(mdl)
+}
+
+// LoadModel reads clusters written by SaveModel from r. The returned
+// clusters have no members, but can be used to predict the clusters of new
+// observations
+func LoadModel(r io.Reader) (clusters.Clusters, error) {
+	var mdl model
+	if err := json.NewDecoder(r).Decode(&mdl); err != nil {
+		return nil, fmt.Errorf("failed to decode model: %s", err)
+	}
+	if len(mdl.Centroids) == 0 {
+		return nil, fmt.Errorf("the model must contain at least one centroid")
+	}
+
+	cc := make(clusters.Clusters, len(mdl.Centroids))
+	for ci, c := range mdl.Centroids {
+		if len(c) == 0 || len(c) != len(mdl.Centroids[0]) {
+			return nil, fmt.Errorf("all centroids must have the same, non-zero number of dimensions")
+		}
+		cc[ci].Center = c
+	}
+	return cc, nil
+}
+
+// Validate checks that the clusters are consistent, e.g. after LoadModel or
+// to debug a custom pipeline: all centers and members must have the same,
+// non-zero number of dimensions, and all center coordinates must be finite.
+// If checkMembership is set, it also checks that every member belongs to a
+// cluster whose center is nearest to it by the configured metric (ties are
+// fine), which takes O(n·k) distance evaluations. The error names the first
+// offending cluster and member
+func (m Kmeans) Validate(cc clusters.Clusters, checkMembership bool) error {
+	if len(cc) == 0 {
+		return nil
+	}
+	dims := len(cc[0].Center)
+	if dims == 0 {
+		return fmt.Errorf("the center of cluster 0 must have at least one dimension")
+	}
+
+	for ci, c := range cc {
+		if len(c.Center) != dims {
+			return fmt.Errorf("the center of cluster %d has %d dimensions, expected %d", ci, len(c.Center), dims)
+		}
+		for j, v := range c.Center {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("coordinate %d of the center of cluster %d is %f", j, ci, v)
+			}
+		}
+		for i, o := range c.Observations {
+			if n := len(o.Coordinates()); n != dims {
+				return fmt.Errorf("member %d of cluster %d has %d dimensions, expected %d", i, ci, n, dims)
+			}
+		}
+	}
+
+	if !checkMembership {
+		return nil
+	}
+	for ci, c := range cc {
+		for i, o := range c.Observations {
+			nearest, d := m.nearest(cc, o)
+			if own := m.distance(o, c.Center); own > d {
+				return fmt.Errorf("member %d of cluster %d is nearer to cluster %d (%f instead of %f)", i, ci, nearest, d, own)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package kmeans
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestSaveModel(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 64; i++ {
+		d = append(d, clusters.Coordinates{float64(i%8) / 8.0, float64(i/8) / 8.0})
+	}
+
+	var buf bytes.Buffer
+	km := New()
+	km.CentroidOutput = &buf
+	cc, err := km.Partition(d, 4)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	var saved bytes.Buffer
+	if err := SaveModel(&saved, cc); err != nil {
+		t.Errorf("Unexpected error saving model: %v", err)
+		return
+	}
+	if buf.String() != saved.String() {
+		t.Errorf("Expected the centroid output %q, got %q", saved.String(), buf.String())
+	}
+
+	loaded, err := LoadModel(&buf)
+	if err != nil {
+		t.Errorf("Unexpected error loading model: %v", err)
+		return
+	}
+	if len(loaded) != len(cc) {
+		t.Errorf("Expected %d clusters, got %d", len(cc), len(loaded))
+		return
+	}
+	for ci := range cc {
+		if !reflect.DeepEqual(loaded[ci].Center, cc[ci].Center) {
+			t.Errorf("Expected center %v, got %v", cc[ci].Center, loaded[ci].Center)
+		}
+	}
+	if !reflect.DeepEqual(km.PredictAll(loaded, d), km.PredictAll(cc, d)) {
+		t.Errorf("Expected the loaded model to predict the same clusters")
+	}
+
+	for _, s := range []string{``, `{"centroids": []}`, `{"centroids": [[0, 1], [0]]}`, `[1, 2]`} {
+		if _, err := LoadModel(strings.NewReader(s)); err == nil {
+			t.Errorf("Expected error loading model %q, got nil", s)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() clusters.Clusters {
+		return clusters.Clusters{
+			{Center: clusters.Coordinates{0, 0}, Observations: clusters.Observations{clusters.Coordinates{0, 1}}},
+			{Center: clusters.Coordinates{4, 0}, Observations: clusters.Observations{clusters.Coordinates{2, 0}, clusters.Coordinates{5, 0}}},
+		}
+	}
+
+	km := New()
+	if err := km.Validate(valid(), true); err != nil {
+		t.Errorf("Unexpected error validating: %v", err)
+	}
+
+	tests := []struct {
+		corrupt func(cc clusters.Clusters)
+		err     string
+	}{
+		{func(cc clusters.Clusters) { cc[1].Center = clusters.Coordinates{1} }, "the center of cluster 1 has 1 dimensions, expected 2"},
+		{func(cc clusters.Clusters) { cc[0].Center[1] = math.NaN() }, "coordinate 1 of the center of cluster 0 is NaN"},
+		{func(cc clusters.Clusters) { cc[1].Center[0] = math.Inf(-1) }, "coordinate 0 of the center of cluster 1 is -Inf"},
+		{func(cc clusters.Clusters) { cc[1].Observations[1] = clusters.Coordinates{5} }, "member 1 of cluster 1 has 1 dimensions, expected 2"},
+		{func(cc clusters.Clusters) { cc[0].Observations[0] = clusters.Coordinates{3, 0} }, "member 0 of cluster 0 is nearer to cluster 1 (1.000000 instead of 9.000000)"},
+	}
+	for _, tt := range tests {
+		cc := valid()
+		tt.corrupt(cc)
+		if err := km.Validate(cc, true); err == nil || err.Error() != tt.err {
+			t.Errorf("Expected error %q, got %v", tt.err, err)
+		}
+	}
+
+	// the membership check is optional
+	cc := valid()
+	cc[0].Observations[0] = clusters.Coordinates{3, 0}
+	if err := km.Validate(cc, false); err != nil {
+		t.Errorf("Unexpected error validating the structure only: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package kmeans
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/k----n/clusters"
+)
+
+// mortonBits is the number of bits of the Morton codes, which get split
+// evenly across the dimensions
+const mortonBits = 64
+
+// mortonOrder returns the indices of the data points sorted by their Morton
+// code: the coordinates get quantized within the bounding box of the
+// dataset, and the bits of all dimensions interleaved, most significant
+// first. Beyond 64 dimensions, only the first 64 get encoded
+func mortonOrder(dataset clusters.Observations) []int {
+	dims := len(dataset[0].Coordinates())
+	if dims > mortonBits {
+		dims = mortonBits
+	}
+	bits := uint(mortonBits / dims)
+	if bits > 32 {
+		// the quantized coordinates must remain exact as float64
+		bits = 32
+	}
+
+	mins := append(clusters.Coordinates{}, dataset[0].Coordinates()[:dims]...)
+	maxs := append(clusters.Coordinates{}, dataset[0].Coordinates()[:dims]...)
+	for _, o := range dataset[1:] {
+		for j, v := range o.Coordinates()[:dims] {
+			if v < mins[j] {
+				mins[j] = v
+			}
+			if v > maxs[j] {
+				maxs[j] = v
+			}
+		}
+	}
+
+	scale := float64(uint64(1)<<bits - 1)
+	codes := make([]uint64, len(dataset))
+	q := make([]uint64, dims)
+	for i, o := range dataset {
+		for j, v := range o.Coordinates()[:dims] {
+			q[j] = 0
+			if maxs[j] > mins[j] {
+				q[j] = uint64((v - mins[j]) / (maxs[j] - mins[j]) * scale)
+			}
+		}
+		var code uint64
+		for b := int(bits) - 1; b >= 0; b-- {
+			for j := range q {
+				code = code<<1 | (q[j]>>uint(b))&1
+			}
+		}
+		codes[i] = code
+	}
+
+	order := make([]int, len(dataset))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return codes[order[a]] < codes[order[b]]
+	})
+	return order
+}
+
+// stepMorton is step for MortonOrder: the data points get assigned in
+// Morton order, and appended to the member lists of their clusters
+// afterwards, in the given order if set, otherwise by index
+func (m Kmeans) stepMorton(cc clusters.Clusters, dataset clusters.Observations, points []int, rng *rand.Rand, frozen []bool, iteration int, lastChanged []int, order []int) (uint64, []int) {
+	morton := m.morton
+	if morton == nil {
+		morton = mortonOrder(dataset)
+	}
+
+	var changes uint64
+	changed := make([]bool, len(dataset))
+	m.forEach(len(dataset), func(n int) {
+		p := morton[n]
+		ci, _ := m.nearest(cc, dataset[p])
+		if points[p] != ci {
+			points[p] = ci
+			changed[p] = true
+		}
+	})
+
+	for n := range dataset {
+		p := n
+		if order != nil {
+			p = order[n]
+		}
+		cc[points[p]].Append(dataset[p])
+		if changed[p] {
+			changes++
+			if lastChanged != nil {
+				lastChanged[p] = iteration
+			}
+		}
+	}
+
+	m.clock.lap(phaseAssignment)
+
+	refilled := m.refillEmpty(cc, dataset, points, rng, frozen)
+	if lastChanged != nil {
+		for _, p := range refilled {
+			lastChanged[p] = iteration
+		}
+	}
+	m.clock.lap(phaseReseed)
+
+	if changes > 0 || len(refilled) > 0 {
+		m.recenter(cc, dataset, points)
+	}
+	m.clock.lap(phaseRecenter)
+	return changes, refilled
+}
@@ -0,0 +1,64 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestMortonOrder(t *testing.T) {
+	// the Z-order visits the quadrants of a grid one after another, the
+	// first dimension being the most significant
+	var d clusters.Observations
+	for i := 0; i < 16; i++ {
+		d = append(d, clusters.Coordinates{float64(i % 4), float64(i / 4)})
+	}
+	exp := []int{0, 4, 1, 5, 8, 12, 9, 13, 2, 6, 3, 7, 10, 14, 11, 15}
+	if order := mortonOrder(d); !reflect.DeepEqual(order, exp) {
+		t.Errorf("Expected order %v, got %v", exp, order)
+	}
+
+	d, _ = MakeBlobs(4096, 8, 2, 1, rand.New(rand.NewSource(randomSeed)))
+	partition := func(morton bool) clusters.Clusters {
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Init = InitKMeansPlusPlus
+		km.MortonOrder = morton
+		cc, err := km.Partition(d, 8)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc
+	}
+	if cc, exp := partition(true), partition(false); !reflect.DeepEqual(cc, exp) {
+		t.Errorf("Expected identical clusters in Morton order")
+	}
+}
+
+func benchmarkStepOrder(morton bool, b *testing.B) {
+	d, _ := MakeBlobs(1<<18, 16, 2, 1, rand.New(rand.NewSource(randomSeed)))
+	cc, err := Kmeans{Init: InitForgy}.seed(16, d, rand.New(rand.NewSource(randomSeed)))
+	if err != nil {
+		b.Fatalf("Unexpected error seeding: %v", err)
+	}
+	assignment := make([]int, len(d))
+
+	// both process the data points the same way, in a different order
+	km := New()
+	km.MortonOrder = true
+	km.morton = mortonOrder(d)
+	if !morton {
+		for i := range km.morton {
+			km.morton[i] = i
+		}
+	}
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		km.step(cc, d, assignment, nil, nil, 0, nil)
+	}
+}
+
+func BenchmarkStepIndexOrder(b *testing.B)  { benchmarkStepOrder(false, b) }
+func BenchmarkStepMortonOrder(b *testing.B) { benchmarkStepOrder(true, b) }
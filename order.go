@@ -0,0 +1,70 @@
+package kmeans
+
+import (
+	"math"
+	"sort"
+
+	"github.com/k----n/clusters"
+)
+
+// OrderKey is a key to order clusters by for output
+type OrderKey int
+
+const (
+	// OrderBySize orders the clusters by their number of members, the
+	// largest first
+	OrderBySize OrderKey = iota
+	// OrderByInertia orders the clusters by the sum of the distances of
+	// their members to their center, by the configured metric, the largest
+	// first. Empty clusters have an inertia of 0
+	OrderByInertia
+	// OrderByNorm orders the clusters by the Euclidean norm of their
+	// center, its distance to the origin, the smallest first
+	OrderByNorm
+	// OrderByFirstDimension orders the clusters by the first coordinate of
+	// their center, the smallest first. Centers without any dimension come
+	// last
+	OrderByFirstDimension
+)
+
+// Ordered returns the indices of the clusters ordered by the given key, a
+// deterministic order to display or report them in, as the order of cc
+// reflects the initialization. Clusters with equal keys keep the order of
+// their indices, and the ones with NaN keys come last. Unlike relabeling,
+// cc is left unchanged. For an unknown key, nil is returned
+func (m Kmeans) Ordered(cc clusters.Clusters, by OrderKey) []int {
+	keys := make([]float64, len(cc))
+	for ci, c := range cc {
+		switch by {
+		case OrderBySize:
+			keys[ci] = -float64(len(c.Observations))
+		case OrderByInertia:
+			for _, o := range c.Observations {
+				keys[ci] -= m.distance(o, c.Center)
+			}
+		case OrderByNorm:
+			var sum float64
+			for _, v := range c.Center {
+				sum += v * v
+			}
+			keys[ci] = math.Sqrt(sum)
+		case OrderByFirstDimension:
+			keys[ci] = math.Inf(1)
+			if len(c.Center) > 0 {
+				keys[ci] = c.Center[0]
+			}
+		default:
+			return nil
+		}
+	}
+
+	order := make([]int, len(cc))
+	for ci := range order {
+		order[ci] = ci
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ka, kb := keys[order[a]], keys[order[b]]
+		return ka < kb || (math.IsNaN(kb) && !math.IsNaN(ka))
+	})
+	return order
+}
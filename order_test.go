@@ -0,0 +1,61 @@
+package kmeans
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestOrdered(t *testing.T) {
+	cc := clusters.Clusters{
+		{
+			Center:       clusters.Coordinates{3, 4},
+			Observations: clusters.Observations{clusters.Coordinates{3, 4}},
+		},
+		{
+			Center: clusters.Coordinates{-1, 0},
+			Observations: clusters.Observations{
+				clusters.Coordinates{-2, 0},
+				clusters.Coordinates{0, 0},
+			},
+		},
+		{
+			Center: clusters.Coordinates{math.NaN(), 0},
+		},
+		{
+			Center: clusters.Coordinates{1, 0},
+			Observations: clusters.Observations{
+				clusters.Coordinates{1, 1},
+				clusters.Coordinates{1, 1},
+				clusters.Coordinates{1, -1},
+			},
+		},
+	}
+
+	km := New()
+	for _, tc := range []struct {
+		by  OrderKey
+		exp []int
+	}{
+		{OrderBySize, []int{3, 1, 0, 2}},
+		// 3, 2, and 0 for both the singleton and the empty cluster
+		{OrderByInertia, []int{3, 1, 0, 2}},
+		{OrderByNorm, []int{1, 3, 0, 2}},
+		{OrderByFirstDimension, []int{1, 3, 0, 2}},
+	} {
+		if order := km.Ordered(cc, tc.by); !reflect.DeepEqual(order, tc.exp) {
+			t.Errorf("Expected order %v by key %d, got %v", tc.exp, tc.by, order)
+		}
+	}
+
+	// ties keep the order of the indices
+	same := clusters.Clusters{{Center: clusters.Coordinates{1}}, {Center: clusters.Coordinates{1}}}
+	if order := km.Ordered(same, OrderByNorm); !reflect.DeepEqual(order, []int{0, 1}) {
+		t.Errorf("Expected order [0 1] for ties, got %v", order)
+	}
+	if order := km.Ordered(cc, OrderKey(-1)); order != nil {
+		t.Errorf("Expected no order for an unknown key, got %v", order)
+	}
+}
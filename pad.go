@@ -0,0 +1,36 @@
+package kmeans
+
+import (
+	"github.com/k----n/clusters"
+)
+
+// padDimensions returns the dataset with all observations padded with zeros
+// to the dimensions of the longest one. Observations which already have
+// those dimensions are kept as they are, and if all of them do, the dataset
+// itself is returned
+func padDimensions(dataset clusters.Observations) clusters.Observations {
+	var dims int
+	for _, o := range dataset {
+		if len(o.Coordinates()) > dims {
+			dims = len(o.Coordinates())
+		}
+	}
+
+	var padded clusters.Observations
+	for i, o := range dataset {
+		if len(o.Coordinates()) == dims {
+			continue
+		}
+		if padded == nil {
+			padded = append(clusters.Observations{}, dataset...)
+		}
+		c := make(clusters.Coordinates, dims)
+		copy(c, o.Coordinates())
+		padded[i] = c
+	}
+
+	if padded == nil {
+		return dataset
+	}
+	return padded
+}
@@ -0,0 +1,48 @@
+package kmeans
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestPadDimensions(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0, 1},
+		clusters.Coordinates{0.5},
+		clusters.Coordinates{10, 10},
+		clusters.Coordinates{10, 11, 0},
+	}
+
+	padded := padDimensions(d)
+	exp := clusters.Observations{
+		clusters.Coordinates{0, 0, 1},
+		clusters.Coordinates{0.5, 0, 0},
+		clusters.Coordinates{10, 10, 0},
+		clusters.Coordinates{10, 11, 0},
+	}
+	if !reflect.DeepEqual(padded, exp) {
+		t.Errorf("Expected padded observations %v, got %v", exp, padded)
+	}
+	if !reflect.DeepEqual(d[1], clusters.Coordinates{0.5}) {
+		t.Errorf("Expected the dataset to remain unchanged, got %v", d[1])
+	}
+	if padded := padDimensions(exp); &padded[0] != &exp[0] {
+		t.Errorf("Expected a dataset without short observations to be returned as is")
+	}
+
+	km := New()
+	km.Init = InitForgy
+	km.PadDimensions = true
+	cc, err := km.Partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	for _, c := range cc {
+		if len(c.Center) != 3 || len(c.Observations) != 2 {
+			t.Errorf("Expected 3-dimensional clusters of 2 observations, got %v", c)
+		}
+	}
+}
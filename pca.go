@@ -0,0 +1,164 @@
+package kmeans
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/k----n/clusters"
+)
+
+// ReduceDimensions projects the dataset onto its dims principal components.
+// Clustering in the reduced space is often faster and less noisy for
+// high-dimensional data. The returned components are unit vectors in the
+// original space, ordered by descending variance: a centroid c of the
+// reduced space maps back to mean + Σ c[j]·components[j]
+// See: https://en.wikipedia.org/wiki/Principal_component_analysis
+func ReduceDimensions(dataset clusters.Observations, dims int) (reduced clusters.Observations, components [][]float64, err error) {
+	if len(dataset) == 0 || len(dataset[0].Coordinates()) == 0 {
+		return nil, nil, fmt.Errorf("there must be at least one dimension in the data set")
+	}
+	d := len(dataset[0].Coordinates())
+	if dims <= 0 || dims > d {
+		return nil, nil, fmt.Errorf("dims is out of bounds (must be between 1 and %d)", d)
+	}
+
+	// Center indexes every data point by the dimensions of the first one
+	for _, o := range dataset {
+		if len(o.Coordinates()) != d {
+			return nil, nil, fmt.Errorf("all observations must have %d dimensions", d)
+		}
+	}
+	mean, err := dataset.Center()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cov := make([][]float64, d)
+	for i := range cov {
+		cov[i] = make([]float64, d)
+	}
+	for _, o := range dataset {
+		c := o.Coordinates()
+		for i := 0; i < d; i++ {
+			for j := i; j < d; j++ {
+				cov[i][j] += (c[i] - mean[i]) * (c[j] - mean[j])
+			}
+		}
+	}
+	for i := 0; i < d; i++ {
+		for j := i; j < d; j++ {
+			cov[i][j] /= float64(len(dataset))
+			cov[j][i] = cov[i][j]
+		}
+	}
+
+	values, vectors := eigenSymmetric(cov)
+	order := make([]int, d)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return values[order[a]] > values[order[b]]
+	})
+
+	components = make([][]float64, dims)
+	for j := range components {
+		v := make([]float64, d)
+		for i := range v {
+			v[i] = vectors[i][order[j]]
+		}
+		// flip the sign so the largest entry is positive, for deterministic
+		// components
+		largest := 0
+		for i := range v {
+			if math.Abs(v[i]) > math.Abs(v[largest]) {
+				largest = i
+			}
+		}
+		if v[largest] < 0 {
+			for i := range v {
+				v[i] = -v[i]
+			}
+		}
+		components[j] = v
+	}
+
+	reduced = make(clusters.Observations, len(dataset))
+	for n, o := range dataset {
+		c := o.Coordinates()
+		r := make(clusters.Coordinates, dims)
+		for j, v := range components {
+			for i := range v {
+				r[j] += (c[i] - mean[i]) * v[i]
+			}
+		}
+		reduced[n] = r
+	}
+
+	return reduced, components, nil
+}
+
+// eigenSymmetric returns the eigenvalues and eigenvectors (as columns) of a
+// symmetric matrix, using the cyclic Jacobi eigenvalue algorithm
+// See: https://en.wikipedia.org/wiki/Jacobi_eigenvalue_algorithm
+func eigenSymmetric(m [][]float64) ([]float64, [][]float64) {
+	n := len(m)
+	a := make([][]float64, n)
+	v := make([][]float64, n)
+	for i := range a {
+		a[i] = append([]float64{}, m[i]...)
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+
+	for sweep := 0; sweep < 100; sweep++ {
+		var off float64
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += a[i][j] * a[i][j]
+			}
+		}
+		if off < 1e-22 {
+			break
+		}
+
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = a[i][i]
+	}
+	return values, v
+}
@@ -0,0 +1,54 @@
+package kmeans
+
+import (
+	"math"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestReduceDimensions(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 16; i++ {
+		x := float64(i) / 16.0
+		// points along the line y = 2x, offset perpendicular to it
+		d = append(d,
+			clusters.Coordinates{x - 0.02, 2*x + 0.01, 0.5},
+			clusters.Coordinates{x + 0.02, 2*x - 0.01, 0.5})
+	}
+
+	reduced, components, err := ReduceDimensions(d, 1)
+	if err != nil {
+		t.Errorf("Unexpected error reducing dimensions: %v", err)
+		return
+	}
+	if len(components) != 1 || len(reduced) != len(d) || len(reduced[0].Coordinates()) != 1 {
+		t.Errorf("Expected 1 component and %d one-dimensional observations, got %d and %d",
+			len(d), len(components), len(reduced))
+		return
+	}
+
+	exp := []float64{1 / math.Sqrt(5), 2 / math.Sqrt(5), 0}
+	for i, v := range components[0] {
+		if math.Abs(v-exp[i]) > 1e-9 {
+			t.Errorf("Expected principal component %v, got %v", exp, components[0])
+			break
+		}
+	}
+
+	// the projections keep the distances along the principal component
+	if r := reduced[30].Coordinates()[0] - reduced[0].Coordinates()[0]; math.Abs(r-15.0/16.0*math.Sqrt(5)) > 1e-9 {
+		t.Errorf("Expected projected distance %f, got %f", 15.0/16.0*math.Sqrt(5), r)
+	}
+
+	if _, _, err := ReduceDimensions(d, 4); err == nil {
+		t.Errorf("Expected error reducing to more dimensions than the data set has, got nil")
+	}
+	if _, _, err := ReduceDimensions(clusters.Observations{}, 1); err == nil {
+		t.Errorf("Expected error reducing an empty data set, got nil")
+	}
+	ragged := clusters.Observations{clusters.Coordinates{1, 2}, clusters.Coordinates{1, 2, 3}}
+	if _, _, err := ReduceDimensions(ragged, 1); err == nil {
+		t.Errorf("Expected error reducing data points of different dimensions, got nil")
+	}
+}
@@ -0,0 +1,101 @@
+package kmeans
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/k----n/classifier/parallel"
+)
+
+// WorkerPool is a fixed set of goroutines processing the parallel loops of
+// a Kmeans, like the assignment and recenter phases of the iterations, so
+// they don't get spawned anew for every data point and every iteration. A
+// WorkerPool may be shared by concurrent calls, e.g. all clusterings of a
+// server, and must be closed once it's no longer needed
+type WorkerPool struct {
+	threads int
+	jobs    chan *poolJob
+	close   sync.Once
+}
+
+// poolJob is a single loop processed by a WorkerPool
+type poolJob struct {
+	n    int
+	body func(i int)
+	// next index to process
+	next atomic.Int64
+	// the workers which took part in the job
+	wg sync.WaitGroup
+}
+
+// NewWorkerPool returns a pool running loops on up to threads goroutines at
+// once, including the calling one
+func NewWorkerPool(threads int) *WorkerPool {
+	if threads <= 0 {
+		threads = 1
+	}
+	p := &WorkerPool{
+		threads: threads,
+		// unbuffered, so a job only gets handed to an idle worker, and
+		// the caller works alone rather than waiting for busy ones
+		jobs: make(chan *poolJob),
+	}
+	for w := 1; w < threads; w++ {
+		go func() {
+			for job := range p.jobs {
+				job.run()
+				job.wg.Done()
+			}
+		}()
+	}
+	return p
+}
+
+// ForEach calls body for every index from 0 to n-1 and returns once all
+// calls returned. The calling goroutine takes part in the loop, so it makes
+// progress even while all workers are busy with other loops
+func (p *WorkerPool) ForEach(n int, body func(i int)) {
+	if n <= 0 {
+		return
+	}
+	job := &poolJob{n: n, body: body}
+	for w := 1; w < p.threads && w < n; w++ {
+		job.wg.Add(1)
+		select {
+		case p.jobs <- job:
+		default:
+			// all workers are busy
+			job.wg.Done()
+		}
+	}
+	job.run()
+	job.wg.Wait()
+}
+
+// Close stops the workers. The pool must not be used afterwards
+func (p *WorkerPool) Close() {
+	p.close.Do(func() {
+		close(p.jobs)
+	})
+}
+
+// run processes indices of the job until none are left
+func (job *poolJob) run() {
+	for {
+		i := int(job.next.Add(1)) - 1
+		if i >= job.n {
+			return
+		}
+		job.body(i)
+	}
+}
+
+// forEach calls fn for every index from 0 to n-1 in parallel, on the
+// configured WorkerPool if set
+func (m Kmeans) forEach(n int, fn func(i int)) {
+	if m.Pool != nil {
+		m.Pool.ForEach(n, fn)
+		return
+	}
+	parallel.ForEach(n, m.Threads, fn)
+}
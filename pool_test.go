@@ -0,0 +1,138 @@
+package kmeans
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/k----n/clusters"
+)
+
+func TestWorkerPool(t *testing.T) {
+	p := NewWorkerPool(4)
+	defer p.Close()
+
+	// concurrent loops on the same pool visit each of their indices once
+	var wg sync.WaitGroup
+	for l := 0; l < 8; l++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			visits := make([]int32, n)
+			p.ForEach(n, func(i int) {
+				atomic.AddInt32(&visits[i], 1)
+			})
+			for i, v := range visits {
+				if v != 1 {
+					t.Errorf("Expected index %d of %d to be visited once, got %d", i, n, v)
+				}
+			}
+		}(l * 100)
+	}
+	wg.Wait()
+
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	for i := 0; i < 2048; i++ {
+		d = append(d, clusters.Coordinates{rng.Float64(), rng.Float64()})
+	}
+	partition := func(pool *WorkerPool, init InitMethod) clusters.Clusters {
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Init = init
+		km.Pool = pool
+		cc, err := km.Partition(d, 8)
+		if err != nil {
+			t.Fatalf("Unexpected error partitioning: %v", err)
+		}
+		return cc
+	}
+	// the order of the members only changes the order of summation
+	for _, init := range []InitMethod{InitKMeansPlusPlus, InitKMeansParallel} {
+		exp, got := partition(nil, init), partition(p, init)
+		for ci := range exp {
+			for j := range exp[ci].Center {
+				if math.Abs(exp[ci].Center[j]-got[ci].Center[j]) > 1e-9 {
+					t.Errorf("Expected center %v with init %d, got %v", exp[ci].Center, init, got[ci].Center)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestWorkerPoolSaturated(t *testing.T) {
+	p := NewWorkerPool(2)
+	defer p.Close()
+
+	// a loop keeping the caller and the only worker busy, retried until
+	// the worker was idle in time to take part
+	var release, done chan struct{}
+	for saturated := false; !saturated; {
+		release, done = make(chan struct{}), make(chan struct{})
+		var entered int32
+		both := make(chan struct{})
+		go func(release, done chan struct{}) {
+			p.ForEach(2, func(i int) {
+				if atomic.AddInt32(&entered, 1) == 2 {
+					close(both)
+				}
+				<-release
+			})
+			close(done)
+		}(release, done)
+		select {
+		case <-both:
+			saturated = true
+		case <-time.After(100 * time.Millisecond):
+			close(release)
+			<-done
+		}
+	}
+
+	// another loop on the saturated pool runs on its caller alone
+	finished := make(chan struct{})
+	go func() {
+		p.ForEach(16, func(i int) {})
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected a loop on a saturated pool not to wait for the busy workers")
+	}
+	close(release)
+	<-done
+}
+
+func benchmarkStep(pool *WorkerPool, b *testing.B) {
+	rand.Seed(randomSeed)
+	var d clusters.Observations
+	for i := 0; i < 65536; i++ {
+		d = append(d, clusters.Coordinates{rand.Float64(), rand.Float64()})
+	}
+	cc, err := Kmeans{Init: InitForgy}.seed(16, d, rand.New(rand.NewSource(randomSeed)))
+	if err != nil {
+		b.Fatalf("Unexpected error seeding: %v", err)
+	}
+	assignment := make([]int, len(d))
+
+	km := New()
+	km.Threads = 8
+	km.Pool = pool
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		km.Step(cc, d, assignment)
+	}
+}
+
+func BenchmarkStepSpawned(b *testing.B) { benchmarkStep(nil, b) }
+
+func BenchmarkStepPooled(b *testing.B) {
+	p := NewWorkerPool(8)
+	defer p.Close()
+	benchmarkStep(p, b)
+}
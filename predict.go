@@ -0,0 +1,330 @@
+package kmeans
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/k----n/clusters"
+)
+
+// streamChunkSize is the number of observations AssignStream holds in memory
+// at once
+const streamChunkSize = 4096
+
+// Predict returns the index of the cluster nearest to the observation.
+// Clusters whose center is at a NaN distance, e.g. because of NaN
+// coordinates, are never nearest, unless all of them are. With
+// SkipEmptyClusters, clusters without members are skipped too
+func (m Kmeans) Predict(cc clusters.Clusters, o clusters.Observation) int {
+	ci, _ := m.predict(cc, o)
+	return ci
+}
+
+// PredictAll returns the index of the nearest cluster for every observation
+// of the dataset, in the order of the dataset. The observations get
+// assigned in parallel, using the configured number of threads
+func (m Kmeans) PredictAll(cc clusters.Clusters, dataset clusters.Observations) []int {
+	assignment := make([]int, len(dataset))
+	m.forEach(len(dataset), func(i int) {
+		assignment[i] = m.Predict(cc, dataset[i])
+	})
+	return assignment
+}
+
+// PredictByID returns the index of the nearest cluster for every
+// observation of the dataset, keyed by its ID: ids[i] is the ID of
+// dataset[i]. IDs must be comparable, like strings and integers, and
+// unique, otherwise an error is returned
+func (m Kmeans) PredictByID(cc clusters.Clusters, dataset clusters.Observations, ids []interface{}) (map[interface{}]int, error) {
+	if len(ids) != len(dataset) {
+		return nil, fmt.Errorf("the number of IDs must equal the size of the data set")
+	}
+	return assignmentByID(m.PredictAll(cc, dataset), ids)
+}
+
+// assignmentByID maps each ID to the cluster index of its data point,
+// failing on duplicate IDs
+func assignmentByID(assignment []int, ids []interface{}) (map[interface{}]int, error) {
+	if len(ids) != len(assignment) {
+		return nil, fmt.Errorf("the number of IDs must equal the size of the data set")
+	}
+	byID := make(map[interface{}]int, len(ids))
+	for i, id := range ids {
+		if _, ok := byID[id]; ok {
+			return nil, fmt.Errorf("ID %v of data point %d is not unique", id, i)
+		}
+		byID[id] = assignment[i]
+	}
+	return byID, nil
+}
+
+// PredictWithDistance returns the index of the nearest cluster for every
+// observation of the dataset along with the distance to that cluster's
+// center, both in the order of the dataset. The observations get assigned
+// in parallel, using the configured number of threads
+func (m Kmeans) PredictWithDistance(cc clusters.Clusters, dataset clusters.Observations) (indices []int, distances []float64) {
+	indices = make([]int, len(dataset))
+	distances = make([]float64, len(dataset))
+	m.forEach(len(dataset), func(i int) {
+		indices[i], distances[i] = m.predict(cc, dataset[i])
+	})
+	return indices, distances
+}
+
+// PredictTopN returns the indices of the n clusters nearest to the
+// observation along with the distances to their centers, both sorted by
+// ascending distance, with ties going to the lower index. The nearest
+// clusters get selected in a single pass over the centers, which costs
+// O(k·n) comparisons, so it's meant for small n. If n is not between 1 and
+// the number of clusters, nil is returned
+func (m Kmeans) PredictTopN(cc clusters.Clusters, o clusters.Observation, n int) ([]int, []float64) {
+	if n < 1 || n > len(cc) {
+		return nil, nil
+	}
+
+	indices := make([]int, 0, n)
+	distances := make([]float64, 0, n)
+	for ci, c := range cc {
+		d := m.distance(o, c.Center)
+		if len(indices) == n {
+			if d >= distances[n-1] {
+				continue
+			}
+			indices, distances = indices[:n-1], distances[:n-1]
+		}
+
+		// insert the cluster behind all nearer or equally near ones
+		pos := len(indices)
+		for pos > 0 && distances[pos-1] > d {
+			pos--
+		}
+		indices = append(indices, 0)
+		distances = append(distances, 0)
+		copy(indices[pos+1:], indices[pos:])
+		copy(distances[pos+1:], distances[pos:])
+		indices[pos], distances[pos] = ci, d
+	}
+	return indices, distances
+}
+
+// PredictMulti returns the index of the cluster nearest to the observation
+// under each of the metrics, e.g. to compare candidate metrics on the same
+// dataset. A nil metric stands for the configured one. Note that the
+// centers were fitted under a single metric, whose means they are, so the
+// assignments under the other metrics are meant for exploration, not as
+// the clusterings those metrics would have produced
+func (m Kmeans) PredictMulti(cc clusters.Clusters, o clusters.Observation, metrics []DistanceFunc) []int {
+	indices := make([]int, len(metrics))
+	for i, metric := range metrics {
+		mm := m
+		if metric != nil {
+			mm.Distance = metric
+		}
+		indices[i], _ = mm.predict(cc, o)
+	}
+	return indices
+}
+
+// Transform returns the distances of every observation of the dataset to
+// every cluster center as an n×k matrix, in the order of the dataset
+func (m Kmeans) Transform(cc clusters.Clusters, dataset clusters.Observations) [][]float64 {
+	dd := make([][]float64, len(dataset))
+	m.forEach(len(dataset), func(i int) {
+		dd[i] = make([]float64, len(cc))
+		for ci, c := range cc {
+			dd[i][ci] = m.distance(dataset[i], c.Center)
+		}
+	})
+	return dd
+}
+
+// Responsibilities returns soft cluster assignments as an n×k matrix, where
+// row i is the softmax of the negative distances of observation i to each
+// cluster center, divided by temperature. Higher temperatures spread the
+// responsibilities more evenly across clusters; as the temperature tends to
+// 0, the responsibilities approach the hard assignment. A temperature of 0
+// or less yields the hard assignment
+func (m Kmeans) Responsibilities(cc clusters.Clusters, dataset clusters.Observations, temperature float64) [][]float64 {
+	rr := m.Transform(cc, dataset)
+	m.forEach(len(rr), func(i int) {
+		r := rr[i]
+		if len(r) == 0 {
+			return
+		}
+
+		nearest := 0
+		for ci := range r {
+			if r[ci] < r[nearest] {
+				nearest = ci
+			}
+		}
+		if temperature <= 0 {
+			for ci := range r {
+				r[ci] = 0
+			}
+			r[nearest] = 1
+			return
+		}
+
+		// shift by the smallest distance to keep the exponents in range
+		shift := r[nearest]
+		var sum float64
+		for ci := range r {
+			r[ci] = math.Exp(-(r[ci] - shift) / temperature)
+			sum += r[ci]
+		}
+		for ci := range r {
+			r[ci] /= sum
+		}
+	})
+	return rr
+}
+
+// AssignStream assigns a stream of observations to their nearest cluster
+// without materializing the entire stream. It pulls observations from next
+// until it returns false, and emits the cluster index of each of them via
+// out, sequentially and in stream order. Observations get assigned in
+// chunks, in parallel using the configured number of threads
+func (m Kmeans) AssignStream(cc clusters.Clusters, next func() (clusters.Observation, bool), out func(index int, ci int)) {
+	chunk := make(clusters.Observations, 0, streamChunkSize)
+	assignment := make([]int, streamChunkSize)
+
+	for index, done := 0, false; !done; {
+		chunk = chunk[:0]
+		for len(chunk) < streamChunkSize {
+			o, ok := next()
+			if !ok {
+				done = true
+				break
+			}
+			chunk = append(chunk, o)
+		}
+
+		m.forEach(len(chunk), func(i int) {
+			assignment[i] = m.Predict(cc, chunk[i])
+		})
+		for i := range chunk {
+			out(index, assignment[i])
+			index++
+		}
+	}
+}
+
+// BoundaryPoints returns the indices of the observations of the dataset
+// which sit near a decision boundary, in the order of the dataset: those
+// whose ratio of the distances to the nearest and the second nearest
+// cluster center is within margin of 1.0. The distances are the ones of the
+// configured metric, i.e. squared distances for clusters.Coordinates. With
+// fewer than two clusters there are no boundaries
+func (m Kmeans) BoundaryPoints(cc clusters.Clusters, dataset clusters.Observations, margin float64) []int {
+	if len(cc) < 2 {
+		return nil
+	}
+
+	ambiguous := make([]bool, len(dataset))
+	m.forEach(len(dataset), func(i int) {
+		_, d1, d2 := m.nearestTwo(cc, dataset[i])
+		// both nearest centers coincide with the observation
+		ratio := 1.0
+		if d2 > 0 {
+			ratio = d1 / d2
+		}
+		ambiguous[i] = 1-ratio <= margin
+	})
+
+	var indices []int
+	for i, a := range ambiguous {
+		if a {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Margins returns, for every observation of the dataset in its order, the
+// gap between its distances to the second nearest and the nearest cluster
+// center, by the configured metric, i.e. of the squared distances for
+// clusters.Coordinates. Small margins mark the observations whose cluster
+// is the least certain, e.g. to rank them for labeling. With a single
+// cluster, all margins are +Inf. The observations get processed in
+// parallel, using the configured number of threads
+func (m Kmeans) Margins(cc clusters.Clusters, dataset clusters.Observations) []float64 {
+	margins := make([]float64, len(dataset))
+	m.forEach(len(dataset), func(i int) {
+		_, d1, d2 := m.nearestTwo(cc, dataset[i])
+		margins[i] = d2 - d1
+	})
+	return margins
+}
+
+// predict returns the index of the cluster nearest to the observation and
+// the distance to its center like nearest, skipping the clusters without
+// members if SkipEmptyClusters is set, unless none of them has any
+func (m Kmeans) predict(cc clusters.Clusters, o clusters.Observation) (int, float64) {
+	if !m.SkipEmptyClusters {
+		return m.nearest(cc, o)
+	}
+
+	ci := -1
+	dist := -1.0
+	for i, c := range cc {
+		if len(c.Observations) == 0 {
+			continue
+		}
+		if d := m.distance(o, c.Center); ci < 0 || closer(d, dist) {
+			dist = d
+			ci = i
+		}
+	}
+	if ci < 0 {
+		// e.g. a loaded model, which has no members
+		return m.nearest(cc, o)
+	}
+	return ci, dist
+}
+
+// nearest returns the index of the cluster nearest to the observation and
+// the distance to its center, looked up in the centroid index during a step
+// which built one
+func (m Kmeans) nearest(cc clusters.Clusters, o clusters.Observation) (int, float64) {
+	if m.index != nil {
+		return m.index.nearest(m, o)
+	}
+	ci := -1
+	dist := -1.0
+
+	for i, c := range cc {
+		d := m.distance(o, c.Center)
+		if ci < 0 || closer(d, dist) {
+			dist = d
+			ci = i
+		}
+	}
+
+	return ci, dist
+}
+
+// closer returns whether the distance d beats the nearest distance so far,
+// which a NaN distance never does, while any other one beats it
+func closer(d, dist float64) bool {
+	return d < dist || (math.IsNaN(dist) && !math.IsNaN(d))
+}
+
+// nearestTwo returns the index of the cluster nearest to the observation
+// along with the distances to the nearest and the second nearest center.
+// With a single cluster, the second distance is +Inf
+func (m Kmeans) nearestTwo(cc clusters.Clusters, o clusters.Observation) (ci int, d1, d2 float64) {
+	ci = -1
+	d1, d2 = math.Inf(1), math.Inf(1)
+
+	for i, c := range cc {
+		d := m.distance(o, c.Center)
+		if ci < 0 || d < d1 {
+			ci, d1, d2 = i, d, d1
+		} else if d < d2 {
+			d2 = d
+		}
+	}
+
+	return ci, d1, d2
+}
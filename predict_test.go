@@ -0,0 +1,286 @@
+package kmeans
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestPredictAll(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}},
+		{Center: clusters.Coordinates{1, 1}},
+	}
+	d := clusters.Observations{
+		clusters.Coordinates{0.1, 0.2},
+		clusters.Coordinates{0.9, 0.7},
+		clusters.Coordinates{0.8, 0.9},
+		clusters.Coordinates{0.3, 0.1},
+	}
+
+	km := New()
+	km.Threads = 4
+	if a := km.PredictAll(cc, d); !reflect.DeepEqual(a, []int{0, 1, 1, 0}) {
+		t.Errorf("Expected assignment [0 1 1 0], got %v", a)
+	}
+}
+
+func TestPredictWithDistance(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}},
+		{Center: clusters.Coordinates{1, 1}},
+	}
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0.5},
+		clusters.Coordinates{1, 0.75},
+	}
+
+	km := New()
+	ii, dd := km.PredictWithDistance(cc, d)
+	if !reflect.DeepEqual(ii, []int{0, 1}) {
+		t.Errorf("Expected assignment [0 1], got %v", ii)
+	}
+	if !reflect.DeepEqual(dd, []float64{0.25, 0.0625}) {
+		t.Errorf("Expected distances [0.25 0.0625], got %v", dd)
+	}
+}
+
+func TestPredictByID(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}},
+		{Center: clusters.Coordinates{1, 1}},
+	}
+	d := clusters.Observations{
+		clusters.Coordinates{0.1, 0.2},
+		clusters.Coordinates{0.9, 0.7},
+		clusters.Coordinates{0.3, 0.1},
+	}
+
+	km := New()
+	byID, err := km.PredictByID(cc, d, []interface{}{"a", 7, "c"})
+	if err != nil {
+		t.Errorf("Unexpected error predicting: %v", err)
+		return
+	}
+	if exp := map[interface{}]int{"a": 0, 7: 1, "c": 0}; !reflect.DeepEqual(byID, exp) {
+		t.Errorf("Expected assignment %v, got %v", exp, byID)
+	}
+
+	if _, err := km.PredictByID(cc, d, []interface{}{"a", "b", "a"}); err == nil {
+		t.Errorf("Expected error predicting with duplicate IDs, got nil")
+	}
+	if _, err := km.PredictByID(cc, d, []interface{}{"a", "b"}); err == nil {
+		t.Errorf("Expected error predicting with too few IDs, got nil")
+	}
+
+	res := Result{Assignment: []int{1, 0}}
+	if byID, err := res.ByID([]interface{}{2, 1}); err != nil || byID[2] != 1 || byID[1] != 0 {
+		t.Errorf("Expected assignment map[1:0 2:1], got %v (%v)", byID, err)
+	}
+}
+
+func TestPredictTopN(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{4}},
+		{Center: clusters.Coordinates{1}},
+		{Center: clusters.Coordinates{-1}},
+		{Center: clusters.Coordinates{3}},
+		{Center: clusters.Coordinates{0}},
+	}
+
+	km := New()
+	ii, dd := km.PredictTopN(cc, clusters.Coordinates{0}, 3)
+	if !reflect.DeepEqual(ii, []int{4, 1, 2}) || !reflect.DeepEqual(dd, []float64{0, 1, 1}) {
+		t.Errorf("Expected clusters [4 1 2] at [0 1 1], got %v at %v", ii, dd)
+	}
+	if ii, _ := km.PredictTopN(cc, clusters.Coordinates{5}, 5); !reflect.DeepEqual(ii, []int{0, 3, 1, 4, 2}) {
+		t.Errorf("Expected all clusters [0 3 1 4 2], got %v", ii)
+	}
+	if ii, _ := km.PredictTopN(cc, clusters.Coordinates{0}, 6); ii != nil {
+		t.Errorf("Expected nil for more clusters than available, got %v", ii)
+	}
+}
+
+func TestPredictMulti(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}},
+		{Center: clusters.Coordinates{2.6, 1}},
+	}
+	manhattan := func(a, b clusters.Coordinates) float64 {
+		var d float64
+		for j := range a {
+			d += math.Abs(a[j] - b[j])
+		}
+		return d
+	}
+	chebyshev := func(a, b clusters.Coordinates) float64 {
+		var d float64
+		for j := range a {
+			d = math.Max(d, math.Abs(a[j]-b[j]))
+		}
+		return d
+	}
+
+	// squared Euclidean 2.56 vs 2, Manhattan 1.6 vs 2, Chebyshev 1.6 vs 1
+	o := clusters.Coordinates{1.6, 0}
+	ii := New().PredictMulti(cc, o, []DistanceFunc{nil, manhattan, chebyshev})
+	if !reflect.DeepEqual(ii, []int{1, 0, 1}) {
+		t.Errorf("Expected nearest clusters [1 0 1], got %v", ii)
+	}
+}
+
+func TestResponsibilities(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0}},
+		{Center: clusters.Coordinates{1}},
+	}
+	d := clusters.Observations{
+		clusters.Coordinates{0.5},
+		clusters.Coordinates{0.25},
+	}
+
+	km := New()
+	if dd := km.Transform(cc, d); !reflect.DeepEqual(dd, [][]float64{{0.25, 0.25}, {0.0625, 0.5625}}) {
+		t.Errorf("Expected distances [[0.25 0.25] [0.0625 0.5625]], got %v", dd)
+	}
+
+	rr := km.Responsibilities(cc, d, 1)
+	if rr[0][0] != 0.5 || rr[0][1] != 0.5 {
+		t.Errorf("Expected equal responsibilities for an equidistant observation, got %v", rr[0])
+	}
+	if exp := 1 / (1 + math.Exp(-0.5)); math.Abs(rr[1][0]-exp) > 1e-12 || math.Abs(rr[1][0]+rr[1][1]-1) > 1e-12 {
+		t.Errorf("Expected responsibilities [%f %f], got %v", exp, 1-exp, rr[1])
+	}
+
+	rr = km.Responsibilities(cc, d, 0.001)
+	if rr[1][0] < 0.999 {
+		t.Errorf("Expected almost hard assignment for a low temperature, got %v", rr[1])
+	}
+	rr = km.Responsibilities(cc, d, 0)
+	if rr[1][0] != 1 || rr[1][1] != 0 {
+		t.Errorf("Expected hard assignment for temperature 0, got %v", rr[1])
+	}
+}
+
+func TestAssignStream(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0}},
+		{Center: clusters.Coordinates{1}},
+	}
+
+	n := streamChunkSize*2 + 3
+	var i int
+	next := func() (clusters.Observation, bool) {
+		if i == n {
+			return nil, false
+		}
+		i++
+		return clusters.Coordinates{float64(i % 2)}, true
+	}
+
+	km := New()
+	km.Threads = 4
+	var count int
+	km.AssignStream(cc, next, func(index int, ci int) {
+		if index != count {
+			t.Errorf("Expected index %d, got %d", count, index)
+		}
+		if exp := (index + 1) % 2; ci != exp {
+			t.Errorf("Expected observation %d in cluster %d, got %d", index, exp, ci)
+		}
+		count++
+	})
+	if count != n {
+		t.Errorf("Expected %d assignments, got %d", n, count)
+	}
+}
+
+func TestBoundaryPoints(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}},
+		{Center: clusters.Coordinates{1, 0}},
+	}
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{0.5, 0.5},
+		clusters.Coordinates{0.45, 0},
+		clusters.Coordinates{1, 0},
+		clusters.Coordinates{0.55, 1},
+	}
+
+	km := New()
+	// squared distance ratios: 0, 1, 0.67, 0, 0.92
+	if b := km.BoundaryPoints(cc, d, 0.2); !reflect.DeepEqual(b, []int{1, 4}) {
+		t.Errorf("Expected boundary points [1 4], got %v", b)
+	}
+	if b := km.BoundaryPoints(cc, d, 0); !reflect.DeepEqual(b, []int{1}) {
+		t.Errorf("Expected boundary points [1], got %v", b)
+	}
+	if b := km.BoundaryPoints(cc[:1], d, 1); b != nil {
+		t.Errorf("Expected no boundary points for a single cluster, got %v", b)
+	}
+}
+
+func TestMargins(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}},
+		{Center: clusters.Coordinates{2, 0}},
+		{Center: clusters.Coordinates{0, 5}},
+	}
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{1, 0},
+		clusters.Coordinates{1.5, 0},
+	}
+
+	km := New()
+	km.Threads = 2
+	// 4 - 0, 1 - 1 and 2.25 - 0.25
+	if margins := km.Margins(cc, d); !reflect.DeepEqual(margins, []float64{4, 0, 2}) {
+		t.Errorf("Expected margins [4 0 2], got %v", margins)
+	}
+	if margins := km.Margins(cc[:1], d); !math.IsInf(margins[0], 1) {
+		t.Errorf("Expected infinite margins for a single cluster, got %v", margins)
+	}
+}
+
+func TestPredictEmptyClusters(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{math.NaN(), 0}},
+		{
+			Center:       clusters.Coordinates{0, 0},
+			Observations: clusters.Observations{clusters.Coordinates{0, 0}},
+		},
+		// stale, without members
+		{Center: clusters.Coordinates{5, 5}},
+		{
+			Center:       clusters.Coordinates{10, 10},
+			Observations: clusters.Observations{clusters.Coordinates{10, 10}},
+		},
+	}
+	d := clusters.Observations{
+		clusters.Coordinates{1, 1},
+		clusters.Coordinates{6, 6},
+		clusters.Coordinates{9, 9},
+	}
+
+	km := New()
+	if p := km.PredictAll(cc, d); !reflect.DeepEqual(p, []int{1, 2, 3}) {
+		t.Errorf("Expected the NaN center to be skipped, got %v", p)
+	}
+	km.SkipEmptyClusters = true
+	if p := km.PredictAll(cc, d); !reflect.DeepEqual(p, []int{1, 3, 3}) {
+		t.Errorf("Expected the empty clusters to be skipped, got %v", p)
+	}
+	if p, dist := km.PredictWithDistance(cc, d[1:2]); p[0] != 3 || dist[0] != 32 {
+		t.Errorf("Expected cluster 3 at distance 32, got %d at %f", p[0], dist[0])
+	}
+
+	// without any members, all clusters are considered
+	loaded := clusters.Clusters{{Center: clusters.Coordinates{0, 0}}, {Center: clusters.Coordinates{5, 5}}}
+	if p := km.PredictAll(loaded, d); !reflect.DeepEqual(p, []int{0, 1, 1}) {
+		t.Errorf("Expected all clusters to be considered, got %v", p)
+	}
+}
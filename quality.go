@@ -0,0 +1,411 @@
+package kmeans
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/k----n/clusters"
+)
+
+// KSelection reports the quality of the clustering found for a number of
+// clusters by SelectK
+type KSelection struct {
+	// K is the number of clusters
+	K int
+	// Inertia is the (weighted) sum of squared distances of the data points
+	// to their cluster center, lower is better
+	Inertia float64
+	// Silhouette is the mean silhouette coefficient, higher is better. It
+	// counts every data point once, regardless of the Weights
+	Silhouette float64
+	// CalinskiHarabasz is the (weighted) Calinski-Harabasz index, higher is
+	// better
+	CalinskiHarabasz float64
+	// DaviesBouldin is the Davies-Bouldin index, lower is better. It counts
+	// every data point once, regardless of the Weights
+	DaviesBouldin float64
+}
+
+// SelectK partitions the dataset into each number of clusters from kMin to
+// kMax (inclusive), with all configured runs and restarts, and reports the
+// quality criteria of each fit, to compare the numbers of clusters. The
+// numbers of clusters get fitted one after the other, each using all
+// configured threads, and each from its own source of randomness seeded
+// from the configured one, so a fit doesn't depend on the other ones.
+// Plotting is disabled during the sweep, and warnings about the dataset
+// are only reported once
+func (m Kmeans) SelectK(dataset clusters.Observations, kMin, kMax int) (results []KSelection, err error) {
+	if kMin < 1 || kMin > kMax || kMax > len(dataset) {
+		return nil, fmt.Errorf("the range of k is out of bounds (must be between 1 and the size of the data set)")
+	}
+	m.plotter = nil
+	if m.PadDimensions {
+		// padded up front as well, for the criteria to measure the same data
+		// points as the fits
+		dataset = padDimensions(dataset)
+	}
+
+	rng := m.rand()
+	seeds := make([]int64, kMax-kMin+1)
+	for i := range seeds {
+		seeds[i] = rng.Int63()
+	}
+
+	// fitting the numbers of clusters in parallel as well would run up to
+	// Threads² goroutines at once
+	results = make([]KSelection, len(seeds))
+	for i, seed := range seeds {
+		mk := m
+		mk.Rand = rand.New(rand.NewSource(seed)) //nolint:gosec // math/rand is good enough for this
+		if i > 0 {
+			mk.Warn = nil
+		}
+		res, err := mk.partition(dataset, kMin+i)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = KSelection{
+			K:                kMin + i,
+			Inertia:          mk.inertia(dataset, res.assignment, res.clusters),
+			Silhouette:       mk.Silhouette(res.clusters),
+			CalinskiHarabasz: mk.weightedCalinskiHarabasz(dataset, res.assignment, res.clusters),
+			DaviesBouldin:    mk.DaviesBouldin(res.clusters),
+		}
+	}
+	return results, nil
+}
+
+// Silhouette returns the mean silhouette coefficient of the members of the
+// clusters: for every member, how much closer it is on average to the other
+// members of its cluster than to the members of the nearest other cluster,
+// between -1 and 1. Members of single-member clusters count as 0. Distances
+// are the square roots of the configured metric, i.e. the Euclidean
+// distances for clusters.Coordinates. It takes O(n²) distance evaluations.
+// With fewer than two non-empty clusters, NaN is returned
+// See: https://en.wikipedia.org/wiki/Silhouette_(clustering)
+func (m Kmeans) Silhouette(cc clusters.Clusters) float64 {
+	type member struct {
+		ci int
+		o  clusters.Observation
+	}
+	var members []member
+	nonEmpty := 0
+	for ci, c := range cc {
+		if len(c.Observations) > 0 {
+			nonEmpty++
+		}
+		for _, o := range c.Observations {
+			members = append(members, member{ci, o})
+		}
+	}
+	if nonEmpty < 2 {
+		return math.NaN()
+	}
+
+	scores := make([]float64, len(members))
+	m.forEach(len(members), func(i int) {
+		own := members[i].ci
+		if len(cc[own].Observations) == 1 {
+			return
+		}
+
+		sums := make([]float64, len(cc))
+		for _, other := range members {
+			sums[other.ci] += math.Sqrt(m.distance(members[i].o, other.o.Coordinates()))
+		}
+		a := sums[own] / float64(len(cc[own].Observations)-1)
+		b := math.Inf(1)
+		for ci, c := range cc {
+			if ci != own && len(c.Observations) > 0 {
+				b = math.Min(b, sums[ci]/float64(len(c.Observations)))
+			}
+		}
+		if s := math.Max(a, b); s > 0 {
+			scores[i] = (b - a) / s
+		}
+	})
+
+	var sum float64
+	for _, s := range scores {
+		sum += s
+	}
+	return sum / float64(len(members))
+}
+
+// CalinskiHarabasz returns the Calinski-Harabasz index of the clusters: the
+// ratio of the dispersion between the cluster centers and the dispersion
+// within the clusters, each divided by its degrees of freedom. Dispersions
+// are sums of the configured (squared) metric. Every member counts once,
+// regardless of the Weights, as the clusters don't tell which data points
+// their members are, see WeightedCalinskiHarabasz. With fewer than two
+// non-empty clusters, or as many clusters as members, NaN is returned
+// See: https://en.wikipedia.org/wiki/Calinski%E2%80%93Harabasz_index
+func (m Kmeans) CalinskiHarabasz(cc clusters.Clusters) float64 {
+	var all clusters.Observations
+	for _, c := range cc {
+		all = append(all, c.Observations...)
+	}
+
+	sizes := make([]float64, len(cc))
+	within := make([]float64, len(cc))
+	m.forEach(len(cc), func(ci int) {
+		c := cc[ci]
+		for _, o := range c.Observations {
+			within[ci] += m.distance(o, c.Center)
+		}
+		sizes[ci] = float64(len(c.Observations))
+	})
+	return m.calinskiHarabasz(cc, sizes, within, m.grandMean(all, nil))
+}
+
+// WeightedCalinskiHarabasz returns the Calinski-Harabasz index of the
+// clusters like CalinskiHarabasz, but over the data points of the dataset,
+// each assigned to the cluster of its nearest center and counting by its
+// weight, if Weights are set, as if it were repeated that many times. If
+// the Weights differ in length from the dataset, NaN is returned
+func (m Kmeans) WeightedCalinskiHarabasz(cc clusters.Clusters, dataset clusters.Observations) float64 {
+	if m.Weights != nil && len(m.Weights) != len(dataset) {
+		return math.NaN()
+	}
+	return m.weightedCalinskiHarabasz(dataset, m.PredictAll(cc, dataset), cc)
+}
+
+// weightedCalinskiHarabasz is WeightedCalinskiHarabasz for the given
+// assignment of the dataset
+func (m Kmeans) weightedCalinskiHarabasz(dataset clusters.Observations, assignment []int, cc clusters.Clusters) float64 {
+	sizes := make([]float64, len(cc))
+	within := make([]float64, len(cc))
+	for i, ci := range assignment {
+		w := 1.0
+		if m.Weights != nil {
+			w = m.Weights[i]
+		}
+		sizes[ci] += w
+		within[ci] += w * m.distance(dataset[i], cc[ci].Center)
+	}
+	return m.calinskiHarabasz(cc, sizes, within, m.grandMean(dataset, m.Weights))
+}
+
+// calinskiHarabasz returns the Calinski-Harabasz index of the clusters from
+// the (weighted) sizes of the clusters, their dispersions within and the
+// mean of all data points
+func (m Kmeans) calinskiHarabasz(cc clusters.Clusters, sizes, within []float64, mean clusters.Coordinates) float64 {
+	k := 0
+	var n, w, b float64
+	for ci, c := range cc {
+		if sizes[ci] > 0 {
+			k++
+			n += sizes[ci]
+			w += within[ci]
+			b += sizes[ci] * m.distance(c.Center, mean)
+		}
+	}
+	if k < 2 || n <= float64(k) {
+		return math.NaN()
+	}
+	if w == 0 {
+		return math.Inf(1)
+	}
+	return (b / float64(k-1)) / (w / (n - float64(k)))
+}
+
+// DaviesBouldin returns the Davies-Bouldin index of the clusters: the mean,
+// over all non-empty clusters, of the highest ratio of the summed spreads
+// of the cluster and another one to the distance between their centers.
+// Spreads are the mean distances of the members to their center, using the
+// square roots of the configured metric like Silhouette. If the centers of
+// two non-empty clusters coincide, e.g. for duplicate data points or frozen
+// centroids, the clusters can't be told apart, and +Inf is returned. With
+// fewer than two non-empty clusters, NaN is returned
+// See: https://en.wikipedia.org/wiki/Davies%E2%80%93Bouldin_index
+func (m Kmeans) DaviesBouldin(cc clusters.Clusters) float64 {
+	var nonEmpty []int
+	for ci, c := range cc {
+		if len(c.Observations) > 0 {
+			nonEmpty = append(nonEmpty, ci)
+		}
+	}
+	if len(nonEmpty) < 2 {
+		return math.NaN()
+	}
+
+	spreads := make([]float64, len(cc))
+	m.forEach(len(nonEmpty), func(n int) {
+		c := cc[nonEmpty[n]]
+		var sum float64
+		for _, o := range c.Observations {
+			sum += math.Sqrt(m.distance(o, c.Center))
+		}
+		spreads[nonEmpty[n]] = sum / float64(len(c.Observations))
+	})
+
+	var sum float64
+	for _, i := range nonEmpty {
+		worst := 0.0
+		for _, j := range nonEmpty {
+			if i == j {
+				continue
+			}
+			d := math.Sqrt(m.distance(cc[i].Center, cc[j].Center))
+			if d == 0 {
+				return math.Inf(1)
+			}
+			worst = math.Max(worst, (spreads[i]+spreads[j])/d)
+		}
+		sum += worst
+	}
+	return sum / float64(len(nonEmpty))
+}
+
+// ExplainedVariance returns the fraction of the total sum of squares of
+// the dataset that lies between the clusters: the sum of squared distances
+// of the cluster centers to the mean of the dataset, weighted by the sizes
+// of the clusters, divided by the sum of squared distances of the data
+// points to that mean. For centers at the means of their members it's 1
+// minus the inertia over the total sum of squares, between 0 (a single
+// cluster) and 1 (every data point its own cluster). If Weights are set,
+// each data point counts by its weight, in the cluster of its nearest
+// center, as the clusters don't tell which data points their members are.
+// If the data points all coincide, or the Weights differ in length from
+// the dataset, NaN is returned
+func (m Kmeans) ExplainedVariance(cc clusters.Clusters, dataset clusters.Observations) float64 {
+	if m.Weights != nil && len(m.Weights) != len(dataset) {
+		return math.NaN()
+	}
+	mean := m.grandMean(dataset, m.Weights)
+	if mean == nil {
+		return math.NaN()
+	}
+	total := m.totalSS(dataset, mean)
+	if total == 0 {
+		return math.NaN()
+	}
+
+	var sizes []float64
+	if m.Weights != nil {
+		sizes = make([]float64, len(cc))
+		for i, ci := range m.PredictAll(cc, dataset) {
+			sizes[ci] += m.Weights[i]
+		}
+	}
+	return m.betweenSS(cc, sizes, mean) / total
+}
+
+// totalSS returns the (weighted) sum of squared distances of the data
+// points to the given mean
+func (m Kmeans) totalSS(dataset clusters.Observations, mean clusters.Coordinates) float64 {
+	var sum float64
+	for i, o := range dataset {
+		d := m.distance(o, mean)
+		if m.Weights != nil {
+			d *= m.Weights[i]
+		}
+		sum += d
+	}
+	return sum
+}
+
+// betweenSS returns the sum of squared distances of the cluster centers to
+// the given mean, each weighted by the size of its cluster, given by sizes
+// unless nil, otherwise by the number of its members
+func (m Kmeans) betweenSS(cc clusters.Clusters, sizes []float64, mean clusters.Coordinates) float64 {
+	var sum float64
+	for ci, c := range cc {
+		size := float64(len(c.Observations))
+		if sizes != nil {
+			size = sizes[ci]
+		}
+		if size > 0 {
+			sum += size * m.distance(c.Center, mean)
+		}
+	}
+	return sum
+}
+
+// Cost returns the (weighted) sum of squared distances of the data points to
+// the centroid of their assigned cluster, measured by the configured metric,
+// for any assignment of the dataset (e.g. one computed elsewhere, as a
+// baseline). It is the inertia Partition minimizes. If the assignment
+// differs in length from the dataset, or refers to a missing centroid, NaN
+// is returned
+func (m Kmeans) Cost(dataset clusters.Observations, assignment []int, centroids []clusters.Coordinates) float64 {
+	if len(assignment) != len(dataset) {
+		return math.NaN()
+	}
+	if m.Weights != nil && len(m.Weights) != len(dataset) {
+		return math.NaN()
+	}
+	for _, ci := range assignment {
+		if ci < 0 || ci >= len(centroids) {
+			return math.NaN()
+		}
+	}
+
+	cc := make(clusters.Clusters, len(centroids))
+	for ci, c := range centroids {
+		cc[ci].Center = c
+	}
+	return m.inertia(dataset, assignment, cc)
+}
+
+// GrandMean returns the mean of all data points of the dataset, the center
+// of a single cluster, in parallel using the configured number of threads.
+// If Weights are set, it's the weighted mean, each data point counting by
+// its weight, like in the cluster centers. For an empty dataset, or one
+// without any weight, nil is returned
+func (m Kmeans) GrandMean(dataset clusters.Observations) clusters.Coordinates {
+	if m.Weights != nil && len(m.Weights) != len(dataset) {
+		return nil
+	}
+	return m.grandMean(dataset, m.Weights)
+}
+
+// grandMean returns the mean of the data points, weighted by weights unless
+// nil
+func (m Kmeans) grandMean(dataset clusters.Observations, weights []float64) clusters.Coordinates {
+	if len(dataset) == 0 {
+		return nil
+	}
+	dims := len(dataset[0].Coordinates())
+
+	chunks := (len(dataset) + recenterChunkSize - 1) / recenterChunkSize
+	sums := make([][]float64, chunks)
+	totals := make([]float64, chunks)
+	m.forEach(chunks, func(chunk int) {
+		end := (chunk + 1) * recenterChunkSize
+		if end > len(dataset) {
+			end = len(dataset)
+		}
+		sum := make([]float64, dims)
+		var total float64
+		for i := chunk * recenterChunkSize; i < end; i++ {
+			w := 1.0
+			if weights != nil {
+				w = weights[i]
+			}
+			for j, v := range dataset[i].Coordinates() {
+				sum[j] += w * v
+			}
+			total += w
+		}
+		sums[chunk], totals[chunk] = sum, total
+	})
+
+	mean := make(clusters.Coordinates, dims)
+	var total float64
+	for chunk := range sums {
+		for j, v := range sums[chunk] {
+			mean[j] += v
+		}
+		total += totals[chunk]
+	}
+	if total == 0 {
+		return nil
+	}
+	for j := range mean {
+		mean[j] /= total
+	}
+	return mean
+}
@@ -0,0 +1,276 @@
+package kmeans
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestQualityCriteria(t *testing.T) {
+	cc := clusters.Clusters{
+		{
+			Center:       clusters.Coordinates{1, 0},
+			Observations: clusters.Observations{clusters.Coordinates{0, 0}, clusters.Coordinates{2, 0}},
+		},
+		{
+			Center:       clusters.Coordinates{11, 0},
+			Observations: clusters.Observations{clusters.Coordinates{10, 0}, clusters.Coordinates{12, 0}},
+		},
+		{
+			Center: clusters.Coordinates{100, 0},
+		},
+	}
+
+	km := New()
+	// a = 2, b = (10 + 12) / 2 = 11 for the outer members, and
+	// a = 2, b = (8 + 10) / 2 = 9 for the inner ones
+	want := ((11.0-2)/11 + (9.0-2)/9) / 2
+	if s := km.Silhouette(cc); math.Abs(s-want) > 1e-9 {
+		t.Errorf("Expected silhouette %f, got %f", want, s)
+	}
+	// B = 2·25 + 2·25 = 100 over 1 degree of freedom, W = 4 over 2
+	if ch := km.CalinskiHarabasz(cc); math.Abs(ch-50) > 1e-9 {
+		t.Errorf("Expected Calinski-Harabasz index 50, got %f", ch)
+	}
+	// (1 + 1) / 10 for both clusters
+	if db := km.DaviesBouldin(cc); math.Abs(db-0.2) > 1e-9 {
+		t.Errorf("Expected Davies-Bouldin index 0.2, got %f", db)
+	}
+
+	same := clusters.Clusters{cc[0], {Center: cc[0].Center, Observations: cc[1].Observations}}
+	if db := km.DaviesBouldin(same); !math.IsInf(db, 1) {
+		t.Errorf("Expected an infinite Davies-Bouldin index for coinciding centers, got %f", db)
+	}
+	same[1].Observations = clusters.Observations{cc[0].Center}
+	same[0].Observations = clusters.Observations{cc[0].Center}
+	if db := km.DaviesBouldin(same); !math.IsInf(db, 1) {
+		t.Errorf("Expected an infinite Davies-Bouldin index for coinciding compact clusters, got %f", db)
+	}
+
+	// the members don't map to weights
+	km.Weights = []float64{2, 1, 1, 1}
+	if ch := km.CalinskiHarabasz(cc); math.Abs(ch-50) > 1e-9 {
+		t.Errorf("Expected unweighted Calinski-Harabasz index 50, got %f", ch)
+	}
+	km.Weights = nil
+
+	if s, ch, db := km.Silhouette(cc[:1]), km.CalinskiHarabasz(cc[:1]), km.DaviesBouldin(cc[:1]); !math.IsNaN(s) || !math.IsNaN(ch) || !math.IsNaN(db) {
+		t.Errorf("Expected NaN criteria for a single cluster, got %f, %f and %f", s, ch, db)
+	}
+}
+
+func TestSelectK(t *testing.T) {
+	// three well separated blobs
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	for i := 0; i < 96; i++ {
+		d = append(d, clusters.Coordinates{
+			float64(i%3)*10 + rng.Float64(),
+			rng.Float64(),
+		})
+	}
+
+	selectK := func(threads int) []KSelection {
+		km := New()
+		km.Init = InitKMeansPlusPlus
+		km.NInit = 4
+		km.Threads = threads
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		results, err := km.SelectK(d, 1, 5)
+		if err != nil {
+			t.Fatalf("Unexpected error selecting k: %v", err)
+		}
+		return results
+	}
+
+	results := selectK(1)
+	if len(results) != 5 {
+		t.Errorf("Expected results for 5 values of k, got %d", len(results))
+		return
+	}
+	best := results[1]
+	for _, r := range results[1:] {
+		if r.Silhouette > best.Silhouette {
+			best = r
+		}
+	}
+	if best.K != 3 {
+		t.Errorf("Expected the best silhouette for 3 clusters, got %d", best.K)
+	}
+	if results[0].K != 1 || !math.IsNaN(results[0].Silhouette) || results[2].Inertia >= results[1].Inertia {
+		t.Errorf("Expected NaN criteria for k=1 and a decreasing inertia, got %+v", results)
+	}
+	if !reflect.DeepEqual(fmtResults(selectK(1)), fmtResults(results)) {
+		t.Errorf("Expected identical results for the same seed")
+	}
+	// more threads only change the order of summation
+	for i, r := range selectK(4) {
+		if r.K != results[i].K || math.Abs(r.Inertia-results[i].Inertia) > 1e-9*results[i].Inertia {
+			t.Errorf("Expected the same fit for k=%d regardless of the number of threads, got %+v and %+v", r.K, r, results[i])
+		}
+	}
+
+	ragged := append(clusters.Observations{clusters.Coordinates{0}}, d[1:]...)
+	km := New()
+	km.PadDimensions = true
+	if _, err := km.SelectK(ragged, 1, 3); err != nil {
+		t.Errorf("Unexpected error selecting k for padded data points: %v", err)
+	}
+	if _, err := New().SelectK(d, 3, 2); err == nil {
+		t.Errorf("Expected error selecting k from an empty range, got nil")
+	}
+}
+
+func TestExplainedVariance(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{10, 0},
+		clusters.Coordinates{12, 0},
+	}
+
+	km := New()
+	cc, err := km.Recenter(d, []int{0, 0, 1, 1}, 2)
+	if err != nil {
+		t.Errorf("Unexpected error recentering: %v", err)
+		return
+	}
+	// total 2*36 + 2*16 = 104, within 4 * 1
+	if ev := km.ExplainedVariance(cc, d); math.Abs(ev-100.0/104) > 1e-12 {
+		t.Errorf("Expected explained variance %f, got %f", 100.0/104, ev)
+	}
+	cc, _ = km.Recenter(d, []int{0, 0, 0, 0}, 1)
+	if ev := km.ExplainedVariance(cc, d); ev != 0 {
+		t.Errorf("Expected no explained variance for a single cluster, got %f", ev)
+	}
+	same := clusters.Observations{clusters.Coordinates{1, 1}, clusters.Coordinates{1, 1}}
+	cc, _ = km.Recenter(same, []int{0, 0}, 1)
+	if ev := km.ExplainedVariance(cc, same); !math.IsNaN(ev) {
+		t.Errorf("Expected NaN for coinciding data points, got %f", ev)
+	}
+}
+
+func TestWeightedCriteria(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{10, 0},
+		clusters.Coordinates{13, 0},
+	}
+	// the same data points, with the first and the last one repeated as
+	// often as their weight
+	repeated := clusters.Observations{d[0], d[0], d[0], d[1], d[2], d[3], d[3]}
+
+	km := New()
+	cc, err := km.Recenter(repeated, []int{0, 0, 0, 0, 1, 1, 1}, 2)
+	if err != nil {
+		t.Errorf("Unexpected error recentering: %v", err)
+		return
+	}
+	want := km.CalinskiHarabasz(cc)
+	if ch := km.weightedCalinskiHarabasz(repeated, []int{0, 0, 0, 0, 1, 1, 1}, cc); math.Abs(ch-want) > 1e-9 {
+		t.Errorf("Expected unweighted Calinski-Harabasz index %f, got %f", want, ch)
+	}
+
+	km.Weights = []float64{3, 1, 1, 2}
+	if ch := km.WeightedCalinskiHarabasz(cc, d); math.Abs(ch-want) > 1e-9 {
+		t.Errorf("Expected weighted Calinski-Harabasz index %f, got %f", want, ch)
+	}
+	if ch := km.WeightedCalinskiHarabasz(cc, d[:3]); !math.IsNaN(ch) {
+		t.Errorf("Expected NaN for mismatching weights, got %f", ch)
+	}
+	km.Weights = nil
+	want = km.ExplainedVariance(cc, repeated)
+	km.Weights = []float64{3, 1, 1, 2}
+	if ev := km.ExplainedVariance(cc, d); math.Abs(ev-want) > 1e-12 {
+		t.Errorf("Expected weighted explained variance %f, got %f", want, ev)
+	}
+	if ev := km.ExplainedVariance(cc, d[:3]); !math.IsNaN(ev) {
+		t.Errorf("Expected NaN for mismatching weights, got %f", ev)
+	}
+}
+
+func TestCost(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{10, 10},
+	}
+	centroids := []clusters.Coordinates{{1, 0}, {10, 11}}
+
+	km := New()
+	if cost := km.Cost(d, []int{0, 0, 1}, centroids); cost != 3 {
+		t.Errorf("Expected cost 3, got %f", cost)
+	}
+	km.Weights = []float64{1, 2, 0.5}
+	if cost := km.Cost(d, []int{0, 0, 1}, centroids); cost != 3.5 {
+		t.Errorf("Expected weighted cost 3.5, got %f", cost)
+	}
+
+	res, err := km.partition(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	centers := []clusters.Coordinates{res.clusters[0].Center, res.clusters[1].Center}
+	if cost, inertia := km.Cost(d, res.assignment, centers), km.inertia(d, res.assignment, res.clusters); cost != inertia {
+		t.Errorf("Expected the cost to equal the inertia %f, got %f", inertia, cost)
+	}
+
+	if cost := km.Cost(d, []int{0, 1}, centroids); !math.IsNaN(cost) {
+		t.Errorf("Expected NaN for a mismatching assignment, got %f", cost)
+	}
+	if cost := km.Cost(d, []int{0, 1, 2}, centroids); !math.IsNaN(cost) {
+		t.Errorf("Expected NaN for a missing centroid, got %f", cost)
+	}
+}
+
+// fmtResults formats k selections along with their NaN criteria
+func fmtResults(results []KSelection) []string {
+	var ss []string
+	for _, r := range results {
+		ss = append(ss, fmtFloat(r.Inertia), fmtFloat(r.Silhouette), fmtFloat(r.CalinskiHarabasz), fmtFloat(r.DaviesBouldin))
+	}
+	return ss
+}
+
+func fmtFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func TestGrandMean(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{4, 6},
+	}
+
+	km := New()
+	if mean := km.GrandMean(d); !reflect.DeepEqual(mean, clusters.Coordinates{2, 2}) {
+		t.Errorf("Expected grand mean [2 2], got %v", mean)
+	}
+	km.Weights = []float64{1, 2, 1}
+	if mean := km.GrandMean(d); !reflect.DeepEqual(mean, clusters.Coordinates{2, 1.5}) {
+		t.Errorf("Expected weighted grand mean [2 1.5], got %v", mean)
+	}
+	km.Weights = []float64{0, 0, 0}
+	if mean := km.GrandMean(d); mean != nil {
+		t.Errorf("Expected no grand mean without any weight, got %v", mean)
+	}
+	if mean := New().GrandMean(nil); mean != nil {
+		t.Errorf("Expected no grand mean of an empty dataset, got %v", mean)
+	}
+
+	// the chunks get merged in order
+	big, _ := MakeBlobs(10000, 4, 3, 1, rand.New(rand.NewSource(randomSeed)))
+	km = New()
+	exp := km.GrandMean(big)
+	km.Threads = 8
+	if mean := km.GrandMean(big); !reflect.DeepEqual(mean, exp) {
+		t.Errorf("Expected the same grand mean regardless of the number of threads, got %v and %v", exp, mean)
+	}
+}
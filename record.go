@@ -0,0 +1,26 @@
+package kmeans
+
+import (
+	"github.com/k----n/clusters"
+)
+
+// Record is an observation carrying an opaque user payload, e.g. the ID of
+// the record the observation was derived from. Partition keeps the
+// observations it was given as cluster members, so the payloads survive the
+// clustering and can be recovered with Payloads
+type Record struct {
+	clusters.Observation
+	Payload interface{}
+}
+
+// Payloads returns the payloads of a cluster's members, in the order of its
+// observations. Members which are not a Record have a nil payload
+func Payloads(c clusters.Cluster) []interface{} {
+	pp := make([]interface{}, len(c.Observations))
+	for i, o := range c.Observations {
+		if r, ok := o.(Record); ok {
+			pp[i] = r.Payload
+		}
+	}
+	return pp
+}
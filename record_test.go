@@ -0,0 +1,46 @@
+package kmeans
+
+import (
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestPayloads(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 64; i++ {
+		d = append(d, Record{
+			Observation: clusters.Coordinates{float64(i%8) / 8.0, float64(i/8) / 8.0},
+			Payload:     i,
+		})
+	}
+
+	km := New()
+	cc, err := km.Partition(d, 4)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+
+	seen := make(map[int]int)
+	for _, c := range cc {
+		for i, p := range Payloads(c) {
+			id, ok := p.(int)
+			if !ok {
+				t.Errorf("Expected an int payload, got %v", p)
+				return
+			}
+			if c.Observations[i].(Record).Payload != id {
+				t.Errorf("Expected payload %d to belong to its observation", id)
+			}
+			seen[id]++
+		}
+	}
+	if len(seen) != len(d) {
+		t.Errorf("Expected payloads of all %d records, got %d", len(d), len(seen))
+	}
+
+	if pp := Payloads(clusters.Cluster{Observations: clusters.Observations{clusters.Coordinates{0}}}); pp[0] != nil {
+		t.Errorf("Expected nil payload for a plain observation, got %v", pp[0])
+	}
+}
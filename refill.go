@@ -0,0 +1,165 @@
+package kmeans
+
+import (
+	"math/rand"
+
+	"github.com/k----n/clusters"
+)
+
+// RefillMethod selects which cluster donates a data point to a cluster
+// which ended up empty
+type RefillMethod int
+
+const (
+	// RefillRandom moves a random data point of any cluster with at least
+	// two members
+	// Also see: http://user.ceng.metu.edu.tr/~tcan/ceng465_f1314/Schedule/KMeansEmpty.html
+	RefillRandom RefillMethod = iota
+	// RefillLargest moves a random member of the cluster with the most
+	// members, which is the least harmed by losing one. Small clusters
+	// never get raided while there are larger ones
+	RefillLargest
+	// RefillHighestSSE moves a random member of the cluster with the
+	// highest (weighted) sum of squared distances of its members to its
+	// center, i.e. the one contributing the most to the inertia
+	RefillHighestSSE
+)
+
+// refillEmpty assigns a data point of a donor cluster, as selected by the
+// configured Refill, to each empty cluster and returns the indices of the
+// data points moved to the refilled clusters, refilling no more than
+// MaxReseedsPerIteration clusters. Frozen clusters never move, so refilling
+// them would be pointless. The clusters get refilled sequentially, so the
+// random picks are reproducible for a seeded source of randomness
+func (m Kmeans) refillEmpty(cc clusters.Clusters, dataset clusters.Observations, assignment []int, rng *rand.Rand, frozen []bool) []int {
+	return m.refillEmptySized(cc, dataset, assignment, rng, frozen, nil)
+}
+
+// refillEmptySized is refillEmpty for clusters whose sizes are given by
+// sizes rather than their member lists, unless nil, as with LeanMemory.
+// The sizes of the refilled and the donor clusters get updated instead of
+// their member lists. Otherwise the donated data points get removed from
+// the member lists of their donors, which get rebuilt from the assignment,
+// so every data point is a member of a single cluster
+func (m Kmeans) refillEmptySized(cc clusters.Clusters, dataset clusters.Observations, assignment []int, rng *rand.Rand, frozen []bool, sizes []int) []int {
+	// number of data points each cluster donated, if sizes is nil
+	var donated []int
+	size := func(ci int) int {
+		if sizes != nil {
+			return sizes[ci]
+		}
+		if donated != nil {
+			return len(cc[ci].Observations) - donated[ci]
+		}
+		return len(cc[ci].Observations)
+	}
+
+	var refilled []int
+	var members [][]int
+	var scores []float64
+	for ci := range cc {
+		if size(ci) != 0 || (frozen != nil && frozen[ci]) {
+			continue
+		}
+		if m.MaxReseedsPerIteration > 0 && len(refilled) >= m.MaxReseedsPerIteration {
+			break
+		}
+
+		var ri int
+		if m.Refill == RefillRandom {
+			for {
+				// find a cluster with at least two data points, otherwise
+				// we're just emptying one cluster to fill another
+				ri = rng.Intn(len(dataset))
+				if size(assignment[ri]) > 1 {
+					break
+				}
+			}
+		} else {
+			if members == nil {
+				members, scores = m.donorScores(cc, dataset, assignment)
+			}
+			donor := -1
+			for di := range cc {
+				if len(members[di]) > 1 && (donor < 0 || scores[di] > scores[donor]) {
+					donor = di
+				}
+			}
+			if donor < 0 {
+				break
+			}
+
+			// remove a random member from the donor and update its score
+			n := rng.Intn(len(members[donor]))
+			ri = members[donor][n]
+			last := len(members[donor]) - 1
+			members[donor][n] = members[donor][last]
+			members[donor] = members[donor][:last]
+			if m.Refill == RefillLargest {
+				scores[donor]--
+			} else {
+				scores[donor] -= m.weightedDistance(dataset, ri, cc[donor].Center)
+			}
+			members[ci] = []int{ri}
+		}
+
+		if sizes != nil {
+			sizes[assignment[ri]]--
+			sizes[ci]++
+		} else {
+			if donated == nil {
+				donated = make([]int, len(cc))
+			}
+			donated[assignment[ri]]++
+			cc[ci].Append(dataset[ri])
+		}
+		assignment[ri] = ci
+		refilled = append(refilled, ri)
+	}
+
+	if donated != nil {
+		for di, n := range donated {
+			if n > 0 {
+				cc[di].Observations = cc[di].Observations[:0]
+			}
+		}
+		for i, di := range assignment {
+			if donated[di] > 0 {
+				cc[di].Append(dataset[i])
+			}
+		}
+	}
+	return refilled
+}
+
+// donorScores returns the indices of each cluster's members and the score
+// of each cluster as a donor for RefillLargest or RefillHighestSSE, i.e.
+// its size or its (weighted) sum of squared distances
+func (m Kmeans) donorScores(cc clusters.Clusters, dataset clusters.Observations, assignment []int) ([][]int, []float64) {
+	members := make([][]int, len(cc))
+	for i, ci := range assignment {
+		members[ci] = append(members[ci], i)
+	}
+
+	scores := make([]float64, len(cc))
+	for ci, mm := range members {
+		if m.Refill == RefillLargest {
+			scores[ci] = float64(len(mm))
+			continue
+		}
+		for _, i := range mm {
+			scores[ci] += m.weightedDistance(dataset, i, cc[ci].Center)
+		}
+	}
+	return members, scores
+}
+
+// weightedDistance returns the distance of data point i to c, multiplied by
+// its weight if Weights are configured
+func (m Kmeans) weightedDistance(dataset clusters.Observations, i int, c clusters.Coordinates) float64 {
+	d := m.distance(dataset[i], c)
+	if m.Weights != nil {
+		d *= m.Weights[i]
+	}
+	return d
+}
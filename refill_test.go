@@ -0,0 +1,139 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestRefill(t *testing.T) {
+	// a large compact cluster, a small loose one and an empty one
+	d := clusters.Observations{
+		clusters.Coordinates{0}, clusters.Coordinates{0.1}, clusters.Coordinates{0.2},
+		clusters.Coordinates{0.3}, clusters.Coordinates{0.4},
+		clusters.Coordinates{100}, clusters.Coordinates{200},
+	}
+	clustered := func() (clusters.Clusters, []int) {
+		cc := clusters.Clusters{{Center: clusters.Coordinates{0.2}}, {Center: clusters.Coordinates{150}}, {Center: clusters.Coordinates{1000}}}
+		assignment := []int{0, 0, 0, 0, 0, 1, 1}
+		for i, ci := range assignment {
+			cc[ci].Append(d[i])
+		}
+		return cc, assignment
+	}
+
+	for refill, donor := range map[RefillMethod]int{RefillLargest: 0, RefillHighestSSE: 1} {
+		for seed := int64(0); seed < 8; seed++ {
+			cc, assignment := clustered()
+			km := Kmeans{Refill: refill}
+			refilled := km.refillEmpty(cc, d, assignment, rand.New(rand.NewSource(randomSeed+seed)), nil)
+			if len(refilled) != 1 || len(cc[2].Observations) != 1 {
+				t.Errorf("Expected a single refilled data point, got %v", refilled)
+				continue
+			}
+			if p := refilled[0]; (donor == 0 && p >= 5) || (donor == 1 && p < 5) || assignment[p] != 2 {
+				t.Errorf("Expected refill method %d to take data point %d from cluster %d, got assignment %v", refill, p, donor, assignment)
+			}
+		}
+	}
+
+	// every cluster gets a member of its own
+	cc := clusters.Clusters{{Center: clusters.Coordinates{0.2}}, {}, {}, {}}
+	assignment := make([]int, len(d))
+	for _, o := range d {
+		cc[0].Append(o)
+	}
+	refilled := Kmeans{Refill: RefillLargest}.refillEmpty(cc, d, assignment, rand.New(rand.NewSource(randomSeed)), nil)
+	if len(refilled) != 3 {
+		t.Errorf("Expected 3 refilled data points, got %v", refilled)
+	}
+	seen := make(map[int]bool)
+	for _, p := range refilled {
+		if seen[p] {
+			t.Errorf("Expected distinct refilled data points, got %v", refilled)
+		}
+		seen[p] = true
+	}
+
+	km := New()
+	km.Init = InitBoundingBox
+	km.Refill = RefillHighestSSE
+	if _, err := km.Partition(d, 3); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+	}
+	km.Refill = RefillMethod(-1)
+	if _, err := km.Partition(d, 3); err == nil {
+		t.Errorf("Expected error partitioning with an unknown refill method, got nil")
+	}
+}
+
+func TestMaxReseedsPerIteration(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 64; i++ {
+		d = append(d, clusters.Coordinates{float64(i) + 10})
+	}
+
+	cc := clusters.Clusters{{Center: clusters.Coordinates{40}}, {}, {}, {}}
+	assignment := make([]int, len(d))
+	for _, o := range d {
+		cc[0].Append(o)
+	}
+	km := Kmeans{MaxReseedsPerIteration: 2}
+	if refilled := km.refillEmpty(cc, d, assignment, rand.New(rand.NewSource(randomSeed)), nil); len(refilled) != 2 {
+		t.Errorf("Expected 2 refilled data points, got %v", refilled)
+	}
+	if len(cc[3].Observations) != 0 {
+		t.Errorf("Expected the last cluster to remain empty, got %v", cc[3].Observations)
+	}
+
+	// all random seeds lie below the data, so all but one cluster start out
+	// empty and recover over several iterations
+	km = New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.MaxReseedsPerIteration = 1
+	cc, err := km.Partition(d, 8)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	for ci, c := range cc {
+		if len(c.Observations) == 0 {
+			t.Errorf("Expected cluster %d to be refilled eventually, got an empty cluster", ci)
+		}
+	}
+}
+
+func TestRefillUnitWeights(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{1, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{10, 0},
+	}
+
+	// the donated data point must leave its donor with or without weights
+	step := func(weights []float64) clusters.Clusters {
+		cc := clusters.Clusters{
+			{Center: clusters.Coordinates{1, 0}},
+			{Center: clusters.Coordinates{10, 0}},
+			{Center: clusters.Coordinates{100, 100}},
+		}
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.Refill = RefillLargest
+		km.Weights = weights
+		if _, err := km.Step(cc, d, make([]int, len(d))); err != nil {
+			t.Fatalf("Unexpected error stepping: %v", err)
+		}
+		return cc
+	}
+
+	exp, cc := step([]float64{1, 1, 1, 1}), step(nil)
+	for ci := range exp {
+		if !reflect.DeepEqual(cc[ci].Center, exp[ci].Center) {
+			t.Errorf("Expected center %v of cluster %d with unit weights, got %v", exp[ci].Center, ci, cc[ci].Center)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package kmeans
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"github.com/k----n/clusters"
+)
+
+// FitSampled executes the k-means algorithm like Fit, but fits the centers
+// on a uniform random sample of the given fraction of the data points
+// (rounded to the nearest count, between 0 exclusive and 1 inclusive),
+// drawn from the source of randomness, for a fast fit on a large dataset.
+// If Weights are set, the sampled data points keep their weights, so the
+// sample stays an unbiased estimate of the weighted dataset. The whole
+// dataset then gets assigned to the fitted centers in a single extra pass,
+// and the returned clusters, assignment and Inertia cover the whole dataset
+// rather than the sample: the Inertia is the Cost of the dataset against
+// the fitted centers, which unlike the inertia of the sample isn't biased
+// low by the sample fitting its own centers. The centers are left as
+// fitted, not recomputed from the whole dataset. The Iterations, Converged
+// and EmptyClusters, as well as the Timings and History of the Stats, refer
+// to the fit of the sample. The LastChanged of the Stats is left nil, even
+// if TrackChanges is set, as the data points outside the sample never
+// shifted during the fit. InitLabels and the CandidatePool, which refer to
+// the data points of the dataset, are ignored
+func (m Kmeans) FitSampled(dataset clusters.Observations, k int, fraction float64) (Result, error) {
+	if fraction <= 0 || fraction > 1 || math.IsNaN(fraction) {
+		return Result{}, fmt.Errorf("the sampling fraction must be greater than 0 and at most 1")
+	}
+	if m.Weights != nil && len(m.Weights) != len(dataset) {
+		return Result{}, fmt.Errorf("the number of weights must equal the size of the data set")
+	}
+	if m.PadDimensions {
+		dataset = padDimensions(dataset)
+	}
+	if m.CountDistances {
+		m.distances = new(atomic.Uint64)
+	}
+
+	picked := sampleIndices(len(dataset), fraction, m.rand())
+	sample := make(clusters.Observations, len(picked))
+	var weights []float64
+	if m.Weights != nil {
+		weights = make([]float64, len(picked))
+	}
+	for i, p := range picked {
+		sample[i] = dataset[p]
+		if weights != nil {
+			weights[i] = m.Weights[p]
+		}
+	}
+
+	ms := m
+	ms.Weights = weights
+	ms.InitLabels = nil
+	ms.CandidatePool = nil
+	ms.TrackChanges = false
+	res, err := ms.partition(sample, k)
+	if err != nil {
+		return Result{}, err
+	}
+
+	cc := make(clusters.Clusters, len(res.clusters))
+	centroids := make([]clusters.Coordinates, len(res.clusters))
+	for ci, c := range res.clusters {
+		cc[ci].Center = c.Center
+		centroids[ci] = c.Center
+	}
+	assignment := m.PredictAll(cc, dataset)
+	for i, ci := range assignment {
+		cc[ci].Observations = append(cc[ci].Observations, dataset[i])
+	}
+	if err := m.writeCentroids(cc); err != nil {
+		return Result{}, err
+	}
+
+	stats := Stats{
+		Timings: res.timings,
+		History: res.history,
+	}
+	if m.distances != nil {
+		stats.DistanceEvaluations = m.distances.Load()
+	}
+	return Result{
+		Clusters:      cc,
+		Assignment:    assignment,
+		Inertia:       m.Cost(dataset, assignment, centroids),
+		Iterations:    res.iterations,
+		Converged:     res.converged,
+		EmptyClusters: res.refills,
+		Stats:         stats,
+	}, nil
+}
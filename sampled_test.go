@@ -0,0 +1,78 @@
+package kmeans
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestFitSampled(t *testing.T) {
+	d, _ := MakeBlobs(5000, 4, 2, 0.5, rand.New(rand.NewSource(randomSeed)))
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Init = InitKMeansPlusPlus
+	res, err := km.FitSampled(d, 4, 0.1)
+	if err != nil {
+		t.Errorf("Unexpected error fitting a sample: %v", err)
+		return
+	}
+	if len(res.Assignment) != len(d) {
+		t.Errorf("Expected an assignment of all %d data points, got %d", len(d), len(res.Assignment))
+		return
+	}
+	members := 0
+	centroids := make([]clusters.Coordinates, len(res.Clusters))
+	for ci, c := range res.Clusters {
+		members += len(c.Observations)
+		centroids[ci] = c.Center
+	}
+	if members != len(d) {
+		t.Errorf("Expected all %d data points as members, got %d", len(d), members)
+	}
+	if cost := km.Cost(d, res.Assignment, centroids); res.Inertia != cost {
+		t.Errorf("Expected the cost %f of the whole dataset as inertia, got %f", cost, res.Inertia)
+	}
+
+	// the same sample gets drawn for the same seed
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	again, err := km.FitSampled(d, 4, 0.1)
+	if err != nil {
+		t.Errorf("Unexpected error fitting a sample: %v", err)
+		return
+	}
+	if again.Inertia != res.Inertia {
+		t.Errorf("Expected the same inertia for the same seed, got %f and %f", res.Inertia, again.Inertia)
+	}
+
+	// the last changes of the sample don't cover the dataset
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.TrackChanges = true
+	tracked, err := km.FitSampled(d, 4, 0.1)
+	if err != nil {
+		t.Errorf("Unexpected error fitting a sample: %v", err)
+		return
+	}
+	if tracked.Stats.LastChanged != nil {
+		t.Errorf("Expected no last changes for a sampled fit, got %d", len(tracked.Stats.LastChanged))
+	}
+	km.TrackChanges = false
+
+	// close to a fit of the whole dataset
+	full, err := km.Fit(d, 4)
+	if err != nil {
+		t.Errorf("Unexpected error fitting: %v", err)
+		return
+	}
+	if res.Inertia < full.Inertia || res.Inertia > 1.1*full.Inertia {
+		t.Errorf("Expected an inertia slightly above %f, got %f", full.Inertia, res.Inertia)
+	}
+
+	for _, f := range []float64{0, 1.5, math.NaN()} {
+		if _, err := km.FitSampled(d, 4, f); err == nil {
+			t.Errorf("Expected error for sampling fraction %f, got nil", f)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package kmeans
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/k----n/clusters"
+)
+
+// Split randomly divides the dataset into a training set holding the given
+// fraction of the data points (rounded to the nearest count) and a test set
+// holding the rest, each in the order of the dataset. All random choices get
+// drawn from rng, so the split is reproducible for a seeded source; when rng
+// is nil, a fresh source is used. If the fraction isn't between 0 and 1,
+// nil is returned.
+//
+// It enables a held-out inertia for choosing k, which unlike the inertia of
+// the fitted data points doesn't keep falling just because more clusters fit
+// the data more closely: for each candidate k, Partition the training set,
+// and compare the Cost of the test set against the fitted centroids, with the
+// assignment of the test set from PredictAll
+func Split(dataset clusters.Observations, fraction float64, rng *rand.Rand) (train, test clusters.Observations) {
+	if fraction < 0 || fraction > 1 || math.IsNaN(fraction) {
+		return nil, nil
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(rand.Int63())) //nolint:gosec // math/rand is good enough for this
+	}
+
+	picked := sampleIndices(len(dataset), fraction, rng)
+	n := len(picked)
+
+	train = make(clusters.Observations, 0, n)
+	test = make(clusters.Observations, 0, len(dataset)-n)
+	for i, o := range dataset {
+		if len(picked) > 0 && picked[0] == i {
+			train = append(train, o)
+			picked = picked[1:]
+			continue
+		}
+		test = append(test, o)
+	}
+	return train, test
+}
+
+// sampleIndices returns the indices of a uniform random sample of the given
+// fraction of n items (rounded to the nearest count), in ascending order
+func sampleIndices(n int, fraction float64, rng *rand.Rand) []int {
+	picked := rng.Perm(n)[:int(math.Round(fraction*float64(n)))]
+	sort.Ints(picked)
+	return picked
+}
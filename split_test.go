@@ -0,0 +1,67 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestSplit(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 10; i++ {
+		d = append(d, clusters.Coordinates{float64(i)})
+	}
+
+	train, test := Split(d, 0.7, rand.New(rand.NewSource(randomSeed)))
+	if len(train) != 7 || len(test) != 3 {
+		t.Errorf("Expected 7 training and 3 test data points, got %d and %d", len(train), len(test))
+		return
+	}
+	seen := make(map[float64]bool)
+	for _, part := range []clusters.Observations{train, test} {
+		for i, o := range part {
+			v := o.Coordinates()[0]
+			if seen[v] || (i > 0 && v < part[i-1].Coordinates()[0]) {
+				t.Errorf("Expected disjoint subsets in the order of the dataset, got %v and %v", train, test)
+			}
+			seen[v] = true
+		}
+	}
+
+	again, _ := Split(d, 0.7, rand.New(rand.NewSource(randomSeed)))
+	if !reflect.DeepEqual(again, train) {
+		t.Errorf("Expected the same split for the same seed, got %v and %v", train, again)
+	}
+	if train, test := Split(d, 1, nil); len(train) != 10 || len(test) != 0 {
+		t.Errorf("Expected all data points in the training set, got %d and %d", len(train), len(test))
+	}
+	if train, test := Split(d, 1.5, nil); train != nil || test != nil {
+		t.Errorf("Expected nil subsets for an invalid fraction, got %v and %v", train, test)
+	}
+}
+
+func TestHeldOutCost(t *testing.T) {
+	d, _ := MakeBlobs(3000, 4, 2, 0.5, rand.New(rand.NewSource(randomSeed)))
+	train, test := Split(d, 0.5, rand.New(rand.NewSource(randomSeed)))
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Init = InitKMeansPlusPlus
+	cc, err := km.Partition(train, 4)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	centroids := make([]clusters.Coordinates, len(cc))
+	for ci, c := range cc {
+		centroids[ci] = c.Center
+	}
+	trainCost := km.Cost(train, km.PredictAll(cc, train), centroids) / float64(len(train))
+	testCost := km.Cost(test, km.PredictAll(cc, test), centroids) / float64(len(test))
+	// the held-out data points are as close to the centroids on average
+	if testCost > 1.2*trainCost {
+		t.Errorf("Expected a held-out cost per data point close to %f, got %f", trainCost, testCost)
+	}
+}
@@ -0,0 +1,130 @@
+package kmeans
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/k----n/classifier/parallel"
+	"github.com/k----n/clusters"
+)
+
+// BootstrapStability measures how reproducible the k clusters of the
+// dataset are: it partitions the dataset once, and then each of nBoot
+// bootstrap samples (drawn with replacement, of the size of the dataset).
+// Each cluster of the base partition gets matched with the cluster of a
+// sample it overlaps best, by the Jaccard index of their data points among
+// the ones in the sample. It returns the mean of the best Jaccard indices
+// across all clusters and samples, between 0 and 1: clusters which keep
+// showing up score close to 1, artifacts of a single partition lower. The
+// samples get clustered in parallel, each from its own source of randomness
+// seeded from the configured one. InitLabels and the CandidatePool, which
+// refer to the data points of the dataset, are ignored, while the Weights
+// get resampled along with the data points. Plotting is disabled, and only
+// the dataset itself is checked for warnings, since the samples are full of
+// duplicates by design
+func (m Kmeans) BootstrapStability(dataset clusters.Observations, k, nBoot int) (meanJaccard float64, err error) {
+	if nBoot < 1 {
+		return 0, fmt.Errorf("the number of bootstrap samples must be greater than 0")
+	}
+	m.plotter = nil
+	m.InitLabels = nil
+	m.CandidatePool = nil
+
+	rng := m.rand()
+	m.Rand = rand.New(rand.NewSource(rng.Int63())) //nolint:gosec // math/rand is good enough for this
+	base, err := m.partition(dataset, k)
+	if err != nil {
+		return 0, err
+	}
+
+	// draw all samples up front, so the order in which they get clustered
+	// doesn't change them
+	samples := make([][]int, nBoot)
+	seeds := make([]int64, nBoot)
+	for b := range samples {
+		samples[b] = make([]int, len(dataset))
+		for i := range samples[b] {
+			samples[b][i] = rng.Intn(len(dataset))
+		}
+		seeds[b] = rng.Int63()
+	}
+
+	sums := make([]float64, nBoot)
+	counts := make([]int, nBoot)
+	errs := make([]error, nBoot)
+	parallel.ForEach(nBoot, m.Threads, func(b int) {
+		mb := m
+		mb.Rand = rand.New(rand.NewSource(seeds[b])) //nolint:gosec // math/rand is good enough for this
+		mb.Warn = nil
+		sample := make(clusters.Observations, len(dataset))
+		var weights []float64
+		if m.Weights != nil {
+			weights = make([]float64, len(dataset))
+		}
+		for i, p := range samples[b] {
+			sample[i] = dataset[p]
+			if weights != nil {
+				weights[i] = m.Weights[p]
+			}
+		}
+		mb.Weights = weights
+
+		res, err := mb.partition(sample, k)
+		if err != nil {
+			errs[b] = err
+			return
+		}
+
+		// cluster of each data point of the dataset in the sample, or -1
+		// if it didn't get drawn
+		drawn := make([]int, len(dataset))
+		for i := range drawn {
+			drawn[i] = -1
+		}
+		for i, p := range samples[b] {
+			if drawn[p] < 0 {
+				drawn[p] = res.assignment[i]
+			}
+		}
+
+		overlap := make([][]int, k)
+		for ci := range overlap {
+			overlap[ci] = make([]int, k)
+		}
+		baseSizes := make([]int, k)
+		sampleSizes := make([]int, k)
+		for p, ci := range drawn {
+			if ci < 0 {
+				continue
+			}
+			overlap[base.assignment[p]][ci]++
+			baseSizes[base.assignment[p]]++
+			sampleSizes[ci]++
+		}
+
+		for bi := range overlap {
+			if baseSizes[bi] == 0 {
+				continue
+			}
+			var best float64
+			for ci, n := range overlap[bi] {
+				if j := float64(n) / float64(baseSizes[bi]+sampleSizes[ci]-n); j > best {
+					best = j
+				}
+			}
+			sums[b] += best
+			counts[b]++
+		}
+	})
+
+	var sum float64
+	var count int
+	for b := range sums {
+		if errs[b] != nil {
+			return 0, errs[b]
+		}
+		sum += sums[b]
+		count += counts[b]
+	}
+	return sum / float64(count), nil
+}
@@ -0,0 +1,43 @@
+package kmeans
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBootstrapStability(t *testing.T) {
+	d, _ := MakeBlobs(300, 3, 2, 0.2, rand.New(rand.NewSource(randomSeed)))
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Init = InitKMeansPlusPlus
+	km.Threads = 4
+	stable, err := km.BootstrapStability(d, 3, 8)
+	if err != nil {
+		t.Errorf("Unexpected error bootstrapping: %v", err)
+		return
+	}
+	if stable < 0.99 {
+		t.Errorf("Expected well separated blobs to be stable, got %f", stable)
+	}
+
+	// splitting the blobs arbitrarily isn't reproducible
+	unstable, err := km.BootstrapStability(d, 9, 8)
+	if err != nil {
+		t.Errorf("Unexpected error bootstrapping: %v", err)
+		return
+	}
+	if unstable >= stable {
+		t.Errorf("Expected too many clusters to be less stable than %f, got %f", stable, unstable)
+	}
+
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	km.Threads = 1
+	if again, _ := km.BootstrapStability(d, 3, 8); again != stable {
+		t.Errorf("Expected the same stability for the same seed, got %f and %f", stable, again)
+	}
+
+	if _, err := km.BootstrapStability(d, 3, 0); err == nil {
+		t.Errorf("Expected error bootstrapping without samples, got nil")
+	}
+}
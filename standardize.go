@@ -0,0 +1,93 @@
+package kmeans
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/k----n/clusters"
+)
+
+// Standardize returns a copy of the dataset with every dimension shifted to
+// a mean of 0 and scaled to a standard deviation of 1, along with the
+// per-dimension means and standard deviations used for the transformation.
+// Dimensions without any variance are only shifted, their standard
+// deviation is reported as 1
+func Standardize(dataset clusters.Observations) (standardized clusters.Observations, means, stddevs []float64, err error) {
+	if len(dataset) == 0 || len(dataset[0].Coordinates()) == 0 {
+		return nil, nil, nil, fmt.Errorf("there must be at least one dimension in the data set")
+	}
+	d := len(dataset[0].Coordinates())
+
+	means = make([]float64, d)
+	stddevs = make([]float64, d)
+	for _, o := range dataset {
+		c := o.Coordinates()
+		if len(c) != d {
+			return nil, nil, nil, fmt.Errorf("all observations must have %d dimensions", d)
+		}
+		for j, v := range c {
+			means[j] += v
+		}
+	}
+	for j := range means {
+		means[j] /= float64(len(dataset))
+	}
+	for _, o := range dataset {
+		for j, v := range o.Coordinates() {
+			stddevs[j] += (v - means[j]) * (v - means[j])
+		}
+	}
+	for j := range stddevs {
+		stddevs[j] = math.Sqrt(stddevs[j] / float64(len(dataset)))
+		if stddevs[j] == 0 {
+			stddevs[j] = 1
+		}
+	}
+
+	standardized = make(clusters.Observations, len(dataset))
+	for i, o := range dataset {
+		c := make(clusters.Coordinates, d)
+		for j, v := range o.Coordinates() {
+			c[j] = (v - means[j]) / stddevs[j]
+		}
+		standardized[i] = c
+	}
+	return standardized, means, stddevs, nil
+}
+
+// Destandardize maps coordinates of the standardized space back into the
+// original units, inverting Standardize
+func Destandardize(c clusters.Coordinates, means, stddevs []float64) clusters.Coordinates {
+	o := make(clusters.Coordinates, len(c))
+	for j, v := range c {
+		o[j] = v*stddevs[j] + means[j]
+	}
+	return o
+}
+
+// PartitionStandardized standardizes the dataset, partitions it into k
+// clusters and maps the result back into the original units: the cluster
+// centers are expressed in the units of the dataset and the members are the
+// original observations. It also returns the cluster index of every
+// observation, in the order of the dataset. Note that all distances, and
+// thus the inertia, are measured in the standardized space
+func (m Kmeans) PartitionStandardized(dataset clusters.Observations, k int) (clusters.Clusters, []int, error) {
+	standardized, means, stddevs, err := Standardize(dataset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := m.partition(standardized, k)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cc := make(clusters.Clusters, len(res.clusters))
+	for ci, c := range res.clusters {
+		cc[ci].Center = Destandardize(c.Center, means, stddevs)
+	}
+	for i, ci := range res.assignment {
+		cc[ci].Append(dataset[i])
+	}
+	return cc, res.assignment, nil
+}
@@ -0,0 +1,63 @@
+package kmeans
+
+import (
+	"math"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestStandardize(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{1, 10, 5},
+		clusters.Coordinates{3, 30, 5},
+	}
+
+	s, means, stddevs, err := Standardize(d)
+	if err != nil {
+		t.Errorf("Unexpected error standardizing: %v", err)
+		return
+	}
+	if s[0].Coordinates()[0] != -1 || s[1].Coordinates()[1] != 1 || s[0].Coordinates()[2] != 0 {
+		t.Errorf("Expected standardized observations, got %v", s)
+	}
+	if stddevs[2] != 1 {
+		t.Errorf("Expected standard deviation 1 for a constant dimension, got %f", stddevs[2])
+	}
+	if c := Destandardize(s[1].Coordinates(), means, stddevs); c[0] != 3 || c[1] != 30 || c[2] != 5 {
+		t.Errorf("Expected [3 30 5] in original units, got %v", c)
+	}
+}
+
+func TestPartitionStandardized(t *testing.T) {
+	var d clusters.Observations
+	for i := 0; i < 16; i++ {
+		d = append(d,
+			clusters.Coordinates{float64(i % 4), 1000 + float64(i/4)},
+			clusters.Coordinates{100 + float64(i%4), 5000 + float64(i/4)})
+	}
+
+	km := New()
+	km.Init = InitForgy
+	km.NInit = 4
+	cc, assignment, err := km.PartitionStandardized(d, 2)
+	if err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(assignment) != len(d) {
+		t.Errorf("Expected %d assignments, got %d", len(d), len(assignment))
+		return
+	}
+
+	for ci, c := range cc {
+		if len(c.Observations) != 16 {
+			t.Errorf("Expected 16 observations in cluster %d, got %d", ci, len(c.Observations))
+		}
+	}
+
+	c := cc[assignment[0]].Center
+	if math.Abs(c[0]-1.5) > 1e-9 || math.Abs(c[1]-1001.5) > 1e-9 {
+		t.Errorf("Expected center [1.5 1001.5] in original units, got %v", c)
+	}
+}
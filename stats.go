@@ -0,0 +1,294 @@
+package kmeans
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/k----n/clusters"
+)
+
+// ClusterVariances returns the per-dimension variance of each cluster's
+// members as a k×d matrix, where d is the dimensionality of the centers.
+// Uneven variances across dimensions indicate elongated clusters. The rows
+// of empty clusters are filled with NaN
+func (m Kmeans) ClusterVariances(cc clusters.Clusters) [][]float64 {
+	vv := make([][]float64, len(cc))
+
+	m.forEach(len(cc), func(ci int) {
+		c := cc[ci]
+		v := make([]float64, len(c.Center))
+		vv[ci] = v
+
+		if len(c.Observations) == 0 {
+			for j := range v {
+				v[j] = math.NaN()
+			}
+			return
+		}
+
+		mean, _ := c.Observations.Center()
+		for _, o := range c.Observations {
+			for j, x := range o.Coordinates() {
+				v[j] += (x - mean[j]) * (x - mean[j])
+			}
+		}
+		for j := range v {
+			v[j] /= float64(len(c.Observations))
+		}
+	})
+
+	return vv
+}
+
+// ClusterCovariances returns the covariance matrix of each cluster's
+// members as a k×d×d set of matrices, where d is the dimensionality of the
+// centers, normalized by the number of members like ClusterVariances, whose
+// rows are their diagonals. Large off-diagonal entries indicate correlated,
+// elongated clusters. A cluster with no more members than dimensions has a
+// singular matrix, which can't be inverted, e.g. for Mahalanobis distances;
+// a single member gives all zeros. The matrices of empty clusters are
+// filled with NaN
+func (m Kmeans) ClusterCovariances(cc clusters.Clusters) [][][]float64 {
+	covs := make([][][]float64, len(cc))
+
+	m.forEach(len(cc), func(ci int) {
+		c := cc[ci]
+		d := len(c.Center)
+		cov := make([][]float64, d)
+		for j := range cov {
+			cov[j] = make([]float64, d)
+		}
+		covs[ci] = cov
+
+		if len(c.Observations) == 0 {
+			for j := range cov {
+				for l := range cov[j] {
+					cov[j][l] = math.NaN()
+				}
+			}
+			return
+		}
+
+		mean, _ := c.Observations.Center()
+		dev := make([]float64, d)
+		for _, o := range c.Observations {
+			for j, x := range o.Coordinates() {
+				dev[j] = x - mean[j]
+			}
+			for j := range cov {
+				for l := j; l < d; l++ {
+					cov[j][l] += dev[j] * dev[l]
+				}
+			}
+		}
+		for j := range cov {
+			for l := j; l < d; l++ {
+				cov[j][l] /= float64(len(c.Observations))
+				cov[l][j] = cov[j][l]
+			}
+		}
+	})
+
+	return covs
+}
+
+// ClusterBounds returns the per-dimension minimum and maximum coordinates
+// of each cluster's members as k×d matrices, where d is the dimensionality
+// of the centers: the bounding box of each cluster. The rows of empty
+// clusters are filled with NaN in both matrices
+func (m Kmeans) ClusterBounds(cc clusters.Clusters) (mins, maxs [][]float64) {
+	mins = make([][]float64, len(cc))
+	maxs = make([][]float64, len(cc))
+
+	m.forEach(len(cc), func(ci int) {
+		c := cc[ci]
+		lo := make([]float64, len(c.Center))
+		hi := make([]float64, len(c.Center))
+		mins[ci], maxs[ci] = lo, hi
+
+		if len(c.Observations) == 0 {
+			for j := range lo {
+				lo[j], hi[j] = math.NaN(), math.NaN()
+			}
+			return
+		}
+
+		copy(lo, c.Observations[0].Coordinates())
+		copy(hi, c.Observations[0].Coordinates())
+		for _, o := range c.Observations[1:] {
+			for j, v := range o.Coordinates() {
+				lo[j] = math.Min(lo[j], v)
+				hi[j] = math.Max(hi[j], v)
+			}
+		}
+	})
+
+	return mins, maxs
+}
+
+// Medoids returns the member of each cluster nearest to its center, i.e. a
+// real observation representing the cluster. Empty clusters have a nil
+// medoid
+func (m Kmeans) Medoids(cc clusters.Clusters) []clusters.Observation {
+	medoids := make([]clusters.Observation, len(cc))
+
+	m.forEach(len(cc), func(ci int) {
+		dist := -1.0
+		for _, o := range cc[ci].Observations {
+			if d := m.distance(o, cc[ci].Center); dist < 0 || d < dist {
+				medoids[ci], dist = o, d
+			}
+		}
+	})
+
+	return medoids
+}
+
+// MedoidIndices returns the index into the dataset of each cluster's
+// medoid, given the cluster index of each data point (as filled in by
+// PartitionInto, or returned by PredictAll). Clusters without data points
+// have a medoid index of -1
+func (m Kmeans) MedoidIndices(cc clusters.Clusters, dataset clusters.Observations, assignment []int) []int {
+	medoids, _ := m.medoidIndices(cc, dataset, assignment)
+	return medoids
+}
+
+// Exemplars returns the index into the dataset of the most central data
+// point of each cluster, the one nearest to its center, along with its
+// distance to the center by the configured metric: a representative example
+// of each cluster. The data points belong to the cluster whose center is
+// nearest, as assigned by PredictAll, which for a converged fit of the
+// dataset are its members. Clusters without data points have an index of
+// -1 and a NaN distance. The dimensions of the data points must match the
+// ones of the centers
+func (m Kmeans) Exemplars(cc clusters.Clusters, dataset clusters.Observations) ([]int, []float64, error) {
+	if len(cc) == 0 {
+		return nil, nil, fmt.Errorf("there must be at least one cluster")
+	}
+	for ci, c := range cc {
+		if len(c.Center) != len(cc[0].Center) {
+			return nil, nil, fmt.Errorf("the center of cluster %d has %d dimensions, expected %d", ci, len(c.Center), len(cc[0].Center))
+		}
+	}
+	for i, o := range dataset {
+		if n := len(o.Coordinates()); n != len(cc[0].Center) {
+			return nil, nil, fmt.Errorf("data point %d has %d dimensions, expected %d", i, n, len(cc[0].Center))
+		}
+	}
+
+	indices, distances := m.medoidIndices(cc, dataset, m.PredictAll(cc, dataset))
+	return indices, distances, nil
+}
+
+// medoidIndices returns the index into the dataset of each cluster's medoid
+// given the assignment, like MedoidIndices, along with its distance to the
+// center, which is NaN for clusters without data points
+func (m Kmeans) medoidIndices(cc clusters.Clusters, dataset clusters.Observations, assignment []int) ([]int, []float64) {
+	members := make([][]int, len(cc))
+	for i, ci := range assignment {
+		members[ci] = append(members[ci], i)
+	}
+
+	medoids := make([]int, len(cc))
+	distances := make([]float64, len(cc))
+	m.forEach(len(cc), func(ci int) {
+		medoids[ci] = -1
+		dist := -1.0
+		for _, i := range members[ci] {
+			if d := m.distance(dataset[i], cc[ci].Center); dist < 0 || d < dist {
+				medoids[ci], dist = i, d
+			}
+		}
+		if medoids[ci] < 0 {
+			dist = math.NaN()
+		}
+		distances[ci] = dist
+	})
+
+	return medoids, distances
+}
+
+// CentroidSeparation returns the distance of each cluster's center to the
+// nearest other center, by the configured metric. Small separations flag
+// redundant clusters, candidates for merging. A single cluster has an
+// infinite separation. It takes O(k²) distance evaluations
+func (m Kmeans) CentroidSeparation(cc clusters.Clusters) []float64 {
+	separation := make([]float64, len(cc))
+
+	m.forEach(len(cc), func(ci int) {
+		separation[ci] = math.Inf(1)
+		for cj := range cc {
+			if cj == ci {
+				continue
+			}
+			if d := m.distance(cc[ci].Center, cc[cj].Center); d < separation[ci] {
+				separation[ci] = d
+			}
+		}
+	})
+
+	return separation
+}
+
+// Cohesion returns the mean distance between all pairs of members of each
+// cluster, by the configured metric, without regard to the centers. Unlike
+// distances to the center, it doesn't assume the center is the true middle
+// of the members, which the mean isn't for most other metrics. Measuring
+// every pair takes O(m²) distance evaluations for a cluster of m members:
+// given a sampleSize, clusters with more pairs than that get their cohesion
+// approximated from sampleSize random pairs instead, drawn from the source
+// of randomness. Single-member clusters have a cohesion of 0, empty ones of
+// NaN
+func (m Kmeans) Cohesion(cc clusters.Clusters, sampleSize ...int) []float64 {
+	samples := 0
+	if len(sampleSize) > 0 {
+		samples = sampleSize[0]
+	}
+	// draw the seeds up front, so the order in which the clusters get
+	// sampled doesn't change the pairs
+	seeds := make([]int64, len(cc))
+	if samples > 0 {
+		rng := m.rand()
+		for ci := range seeds {
+			seeds[ci] = rng.Int63()
+		}
+	}
+
+	cohesion := make([]float64, len(cc))
+	m.forEach(len(cc), func(ci int) {
+		members := cc[ci].Observations
+		n := len(members)
+		switch {
+		case n == 0:
+			cohesion[ci] = math.NaN()
+			return
+		case n == 1:
+			return
+		}
+
+		var sum float64
+		if pairs := n * (n - 1) / 2; samples <= 0 || pairs <= samples {
+			for i := range members {
+				for j := i + 1; j < n; j++ {
+					sum += m.distance(members[i], members[j].Coordinates())
+				}
+			}
+			cohesion[ci] = sum / float64(pairs)
+			return
+		}
+
+		rng := rand.New(rand.NewSource(seeds[ci])) //nolint:gosec // math/rand is good enough for this
+		for s := 0; s < samples; s++ {
+			i := rng.Intn(n)
+			j := rng.Intn(n - 1)
+			if j >= i {
+				j++
+			}
+			sum += m.distance(members[i], members[j].Coordinates())
+		}
+		cohesion[ci] = sum / float64(samples)
+	})
+
+	return cohesion
+}
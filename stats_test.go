@@ -0,0 +1,224 @@
+package kmeans
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestClusterVariances(t *testing.T) {
+	cc := clusters.Clusters{
+		{
+			Center: clusters.Coordinates{2, 1},
+			Observations: clusters.Observations{
+				clusters.Coordinates{1, 1},
+				clusters.Coordinates{3, 1},
+			},
+		},
+		{
+			Center: clusters.Coordinates{0, 0},
+		},
+	}
+
+	km := New()
+	vv := km.ClusterVariances(cc)
+	if len(vv) != 2 {
+		t.Errorf("Expected variances of 2 clusters, got %d", len(vv))
+		return
+	}
+	if vv[0][0] != 1 || vv[0][1] != 0 {
+		t.Errorf("Expected variances [1 0], got %v", vv[0])
+	}
+	if !math.IsNaN(vv[1][0]) || !math.IsNaN(vv[1][1]) {
+		t.Errorf("Expected NaN variances for an empty cluster, got %v", vv[1])
+	}
+}
+
+func TestClusterCovariances(t *testing.T) {
+	cc := clusters.Clusters{
+		{
+			Center: clusters.Coordinates{2, 2},
+			Observations: clusters.Observations{
+				clusters.Coordinates{1, 1},
+				clusters.Coordinates{3, 3},
+				clusters.Coordinates{1, 3},
+				clusters.Coordinates{3, 1},
+				clusters.Coordinates{0, 0},
+				clusters.Coordinates{4, 4},
+			},
+		},
+		{
+			// singular, fewer members than dimensions
+			Center:       clusters.Coordinates{0, 0},
+			Observations: clusters.Observations{clusters.Coordinates{0, 0}},
+		},
+		{
+			Center: clusters.Coordinates{0, 0},
+		},
+	}
+
+	km := New()
+	covs := km.ClusterCovariances(cc)
+	if len(covs) != 3 {
+		t.Errorf("Expected covariances of 3 clusters, got %d", len(covs))
+		return
+	}
+	// (1+1+1+1+4+4)/6 = 2 on the diagonal, (1+1-1-1+4+4)/6 = 4/3 off it
+	exp := [][]float64{{2, 4.0 / 3}, {4.0 / 3, 2}}
+	for j := range exp {
+		for l := range exp[j] {
+			if math.Abs(covs[0][j][l]-exp[j][l]) > 1e-12 {
+				t.Errorf("Expected covariances %v, got %v", exp, covs[0])
+			}
+		}
+	}
+	vv := km.ClusterVariances(cc)
+	if covs[0][0][0] != vv[0][0] || covs[0][1][1] != vv[0][1] {
+		t.Errorf("Expected the variances %v on the diagonal, got %v", vv[0], covs[0])
+	}
+	if !reflect.DeepEqual(covs[1], [][]float64{{0, 0}, {0, 0}}) {
+		t.Errorf("Expected zero covariances for a single member, got %v", covs[1])
+	}
+	if !math.IsNaN(covs[2][0][1]) || !math.IsNaN(covs[2][1][1]) {
+		t.Errorf("Expected NaN covariances for an empty cluster, got %v", covs[2])
+	}
+}
+
+func TestClusterBounds(t *testing.T) {
+	cc := clusters.Clusters{
+		{
+			Center: clusters.Coordinates{1, 1},
+			Observations: clusters.Observations{
+				clusters.Coordinates{0, 2},
+				clusters.Coordinates{2, -1},
+				clusters.Coordinates{1, 0.5},
+			},
+		},
+		{Center: clusters.Coordinates{5, 5}},
+	}
+
+	mins, maxs := New().ClusterBounds(cc)
+	if len(mins) != 2 || !reflect.DeepEqual(mins[0], []float64{0, -1}) || !reflect.DeepEqual(maxs[0], []float64{2, 2}) {
+		t.Errorf("Expected bounds [0 -1] to [2 2], got %v to %v", mins, maxs)
+	}
+	for j := range mins[1] {
+		if !math.IsNaN(mins[1][j]) || !math.IsNaN(maxs[1][j]) {
+			t.Errorf("Expected NaN bounds for an empty cluster, got %v to %v", mins[1], maxs[1])
+		}
+	}
+}
+
+func TestMedoids(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{1, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{10, 0},
+		clusters.Coordinates{12, 0},
+	}
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0.9, 0}, Observations: d[:3]},
+		{Center: clusters.Coordinates{11.5, 0}, Observations: d[3:]},
+		{Center: clusters.Coordinates{20, 0}},
+	}
+
+	km := New()
+	medoids := km.Medoids(cc)
+	if len(medoids) != 3 || medoids[0].Coordinates()[0] != 1 || medoids[1].Coordinates()[0] != 12 || medoids[2] != nil {
+		t.Errorf("Expected medoids [1 0], [12 0] and nil, got %v", medoids)
+	}
+
+	indices := km.MedoidIndices(cc, d, []int{0, 0, 0, 1, 1})
+	if len(indices) != 3 || indices[0] != 1 || indices[1] != 4 || indices[2] != -1 {
+		t.Errorf("Expected medoid indices [1 4 -1], got %v", indices)
+	}
+}
+
+func TestExemplars(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 0},
+		clusters.Coordinates{1, 0},
+		clusters.Coordinates{2, 0},
+		clusters.Coordinates{10, 0},
+		clusters.Coordinates{12, 0},
+	}
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0.9, 0}},
+		{Center: clusters.Coordinates{11.5, 0}},
+		{Center: clusters.Coordinates{20, 0}},
+	}
+
+	km := New()
+	indices, distances, err := km.Exemplars(cc, d)
+	if err != nil {
+		t.Errorf("Unexpected error finding exemplars: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(indices, []int{1, 4, -1}) {
+		t.Errorf("Expected exemplars [1 4 -1], got %v", indices)
+	}
+	if math.Abs(distances[0]-0.01) > 1e-12 || distances[1] != 0.25 || !math.IsNaN(distances[2]) {
+		t.Errorf("Expected distances [0.01 0.25 NaN], got %v", distances)
+	}
+
+	if _, _, err := km.Exemplars(nil, d); err == nil {
+		t.Errorf("Expected error without clusters, got nil")
+	}
+	if _, _, err := km.Exemplars(cc, clusters.Observations{clusters.Coordinates{1}}); err == nil {
+		t.Errorf("Expected error for mismatching dimensions, got nil")
+	}
+}
+
+func TestCentroidSeparation(t *testing.T) {
+	cc := clusters.Clusters{
+		{Center: clusters.Coordinates{0, 0}},
+		{Center: clusters.Coordinates{1, 0}},
+		{Center: clusters.Coordinates{10, 0}},
+	}
+
+	km := New()
+	if sep := km.CentroidSeparation(cc); !reflect.DeepEqual(sep, []float64{1, 1, 81}) {
+		t.Errorf("Expected separations [1 1 81], got %v", sep)
+	}
+	km.FeatureWeights = []float64{2, 1}
+	if sep := km.CentroidSeparation(cc); !reflect.DeepEqual(sep, []float64{2, 2, 162}) {
+		t.Errorf("Expected weighted separations [2 2 162], got %v", sep)
+	}
+	if sep := km.CentroidSeparation(cc[:1]); !math.IsInf(sep[0], 1) {
+		t.Errorf("Expected an infinite separation of a single cluster, got %v", sep)
+	}
+}
+
+func TestCohesion(t *testing.T) {
+	cc := clusters.Clusters{
+		{Observations: clusters.Observations{clusters.Coordinates{0, 0}, clusters.Coordinates{1, 0}, clusters.Coordinates{0, 2}}},
+		{Observations: clusters.Observations{clusters.Coordinates{5, 5}}},
+		{},
+	}
+
+	km := New()
+	// (1 + 4 + 5) / 3 pairs
+	coh := km.Cohesion(cc)
+	if len(coh) != 3 || math.Abs(coh[0]-10.0/3) > 1e-12 || coh[1] != 0 || !math.IsNaN(coh[2]) {
+		t.Errorf("Expected cohesions [3.333333 0 NaN], got %v", coh)
+	}
+	if exact := km.Cohesion(cc, 3); exact[0] != coh[0] {
+		t.Errorf("Expected the exact cohesion for as many samples as pairs, got %f", exact[0])
+	}
+
+	var big clusters.Observations
+	rng := rand.New(rand.NewSource(randomSeed))
+	for i := 0; i < 200; i++ {
+		big = append(big, clusters.Coordinates{rng.Float64(), rng.Float64()})
+	}
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	exact := km.Cohesion(clusters.Clusters{{Observations: big}})[0]
+	approx := km.Cohesion(clusters.Clusters{{Observations: big}}, 2000)[0]
+	// uniform in the unit square: E[d²] = 2 * 2 * 1/12
+	if math.Abs(exact-1.0/3) > 0.05 || math.Abs(approx-exact) > 0.05 {
+		t.Errorf("Expected a cohesion of about %f, got %f exactly and %f sampled", 1.0/3, exact, approx)
+	}
+}
@@ -0,0 +1,106 @@
+package kmeans
+
+import (
+	"github.com/k----n/clusters"
+)
+
+// snapshotBuffer is the number of snapshots PartitionStream buffers for a
+// slow consumer
+const snapshotBuffer = 16
+
+// Snapshot is the state of a clustering after an iteration, as emitted by
+// PartitionStream
+type Snapshot struct {
+	// Centroids are copies of the cluster centers
+	Centroids []clusters.Coordinates
+	// Iteration counts the iterations of the current run from 0
+	Iteration int
+	// Changes is the number of data points which shifted clusters in the
+	// iteration, where each refilled empty cluster counts as the size of
+	// the data set, as in the convergence check
+	Changes int
+	// Inertia is the (weighted) sum of squared distances of the data points
+	// to their cluster center
+	Inertia float64
+	// Final marks the snapshot of the returned clustering, which always
+	// gets emitted last. Its Iteration and Changes are zero
+	Final bool
+}
+
+// snapshotStream is the sending side of the channel of PartitionStream
+type snapshotStream chan Snapshot
+
+// PartitionStream executes the k-means algorithm like Partition in the
+// background, and emits a snapshot of the clustering after each iteration
+// of every run on the first channel, followed by the final snapshot of the
+// returned clustering. If the consumer falls more than a few snapshots
+// behind, further ones get dropped (except the final one, which replaces
+// the oldest buffered one), so the algorithm never stalls. Once the
+// clustering is complete, the snapshot channel gets closed and the outcome
+// (nil on success) gets sent on the second one, which is closed afterwards
+// too. Computing the inertia of every snapshot takes an extra pass over the
+// data per iteration. A configured plotter still gets called synchronously
+func (m Kmeans) PartitionStream(dataset clusters.Observations, k int) (<-chan Snapshot, <-chan error) {
+	snapshots := make(snapshotStream, snapshotBuffer)
+	errs := make(chan error, 1)
+	m.snapshots = snapshots
+
+	go func() {
+		defer close(errs)
+		defer close(snapshots)
+
+		res, err := m.partition(dataset, k)
+		if err == nil {
+			err = m.writeCentroids(res.clusters)
+		}
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		final := snapshotOf(res.clusters, 0, 0, m.inertia(dataset, res.assignment, res.clusters))
+		final.Final = true
+		snapshots.push(final)
+		errs <- nil
+	}()
+
+	return snapshots, errs
+}
+
+// snapshotOf returns a snapshot of the clusters
+func snapshotOf(cc clusters.Clusters, iteration, changes int, inertia float64) Snapshot {
+	centroids := make([]clusters.Coordinates, len(cc))
+	for ci, c := range cc {
+		centroids[ci] = append(clusters.Coordinates{}, c.Center...)
+	}
+	return Snapshot{
+		Centroids: centroids,
+		Iteration: iteration,
+		Changes:   changes,
+		Inertia:   inertia,
+	}
+}
+
+// emit sends the snapshot unless the buffer is full
+func (s snapshotStream) emit(snapshot Snapshot) {
+	select {
+	case s <- snapshot:
+	default:
+	}
+}
+
+// push sends the snapshot, dropping the oldest buffered ones to make room
+// for it
+func (s snapshotStream) push(snapshot Snapshot) {
+	for {
+		select {
+		case s <- snapshot:
+			return
+		default:
+		}
+		select {
+		case <-s:
+		default:
+		}
+	}
+}
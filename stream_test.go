@@ -0,0 +1,73 @@
+package kmeans
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestPartitionStream(t *testing.T) {
+	rng := rand.New(rand.NewSource(randomSeed))
+	var d clusters.Observations
+	for i := 0; i < 512; i++ {
+		d = append(d, clusters.Coordinates{rng.Float64(), rng.Float64()})
+	}
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	snapshots, errs := km.PartitionStream(d, 8)
+	var all []Snapshot
+	for s := range snapshots {
+		all = append(all, s)
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	if len(all) < 2 {
+		t.Errorf("Expected snapshots of the iterations, got %v", all)
+		return
+	}
+	for i, s := range all[:len(all)-1] {
+		if s.Final || s.Iteration != i || len(s.Centroids) != 8 {
+			t.Errorf("Expected snapshot of iteration %d, got %+v", i, s)
+		}
+	}
+
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	cc, _ := km.Partition(d, 8)
+	final := all[len(all)-1]
+	if !final.Final || final.Inertia <= 0 {
+		t.Errorf("Expected the final snapshot last, got %+v", final)
+	}
+	for ci, c := range cc {
+		if !reflect.DeepEqual(c.Center, final.Centroids[ci]) {
+			t.Errorf("Expected final centroid %v, got %v", c.Center, final.Centroids[ci])
+		}
+	}
+
+	// a consumer which doesn't keep up misses snapshots, but not the final
+	// one
+	km.NInit = 8
+	snapshots, errs = km.PartitionStream(d, 8)
+	if err := <-errs; err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+	}
+	all = all[:0]
+	for s := range snapshots {
+		all = append(all, s)
+	}
+	if len(all) > snapshotBuffer || !all[len(all)-1].Final {
+		t.Errorf("Expected at most %d snapshots ending in the final one, got %d", snapshotBuffer, len(all))
+	}
+
+	snapshots, errs = km.PartitionStream(d, 0)
+	if err := <-errs; err == nil {
+		t.Errorf("Expected error partitioning into 0 clusters, got nil")
+	}
+	if _, ok := <-snapshots; ok {
+		t.Errorf("Expected the snapshot channel to be closed")
+	}
+}
@@ -0,0 +1,64 @@
+package kmeans
+
+import (
+	"time"
+)
+
+// IterationTimings reports how long the phases of an iteration took
+type IterationTimings struct {
+	// Assignment is the time spent assigning the data points to their
+	// nearest cluster. With FusedRecenter, it includes summing up the
+	// members of each cluster
+	Assignment time.Duration
+	// Reseed is the time spent refilling empty clusters
+	Reseed time.Duration
+	// Recenter is the time spent moving the centers to their members
+	Recenter time.Duration
+}
+
+// phase is one of the phases of an iteration, in the order of
+// IterationTimings
+type phase int
+
+const (
+	phaseAssignment phase = iota
+	phaseReseed
+	phaseRecenter
+)
+
+// phaseClock measures the phases of an iteration on the monotonic clock. A
+// nil clock measures nothing, so steps can time their phases
+// unconditionally
+type phaseClock struct {
+	last    time.Time
+	current [3]time.Duration
+}
+
+// start marks the beginning of the first phase
+func (c *phaseClock) start() {
+	if c != nil {
+		c.last = time.Now()
+	}
+}
+
+// lap adds the time since the end of the previous phase to the given one
+func (c *phaseClock) lap(p phase) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	c.current[p] += now.Sub(c.last)
+	c.last = now
+}
+
+// take returns the timings of the iteration and resets the clock for the
+// next one
+func (c *phaseClock) take() IterationTimings {
+	t := IterationTimings{
+		Assignment: c.current[phaseAssignment],
+		Reseed:     c.current[phaseReseed],
+		Recenter:   c.current[phaseRecenter],
+	}
+	c.current = [3]time.Duration{}
+	return t
+}
@@ -0,0 +1,37 @@
+package kmeans
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTrackTimings(t *testing.T) {
+	d, _ := MakeBlobs(4096, 8, 2, 1, rand.New(rand.NewSource(randomSeed)))
+
+	for _, fused := range []bool{false, true} {
+		km := New()
+		km.Rand = rand.New(rand.NewSource(randomSeed))
+		km.FusedRecenter = fused
+		km.TrackTimings = true
+		res, err := km.Fit(d, 8)
+		if err != nil {
+			t.Errorf("Unexpected error partitioning: %v", err)
+			return
+		}
+		if len(res.Stats.Timings) != res.Iterations {
+			t.Errorf("Expected timings of %d iterations, got %d", res.Iterations, len(res.Stats.Timings))
+			continue
+		}
+		for i, tt := range res.Stats.Timings {
+			if tt.Assignment <= 0 || tt.Reseed < 0 || tt.Recenter < 0 {
+				t.Errorf("Expected positive durations of iteration %d, got %+v", i, tt)
+			}
+		}
+	}
+
+	km := New()
+	km.Rand = rand.New(rand.NewSource(randomSeed))
+	if res, _ := km.Fit(d, 8); res.Stats.Timings != nil {
+		t.Errorf("Expected no timings without TrackTimings, got %d", len(res.Stats.Timings))
+	}
+}
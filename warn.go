@@ -0,0 +1,68 @@
+package kmeans
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/k----n/clusters"
+)
+
+// scaleDisparity is the ratio of the largest to the smallest standard
+// deviation of the dimensions above which warn suggests standardizing
+const scaleDisparity = 100
+
+// warn checks the dataset for quirks which don't keep it from being
+// clustered, but likely distort the result, and reports each of them to the
+// configured Warn. It doesn't run without a Warn, since the checks take
+// another two passes over the data
+func (m Kmeans) warn(dataset clusters.Observations) {
+	if m.Warn == nil || len(dataset) == 0 {
+		return
+	}
+
+	seen := make(map[string]struct{})
+	var buf []byte
+	for _, o := range dataset {
+		buf = dedupeKey(buf[:0], o)
+		seen[string(buf)] = struct{}{}
+	}
+	if duplicates := len(dataset) - len(seen); duplicates > 0 {
+		m.Warn(fmt.Sprintf("%d duplicate observations", duplicates))
+	}
+
+	d := len(dataset[0].Coordinates())
+	means := make([]float64, d)
+	for _, o := range dataset {
+		c := o.Coordinates()
+		if len(c) != d {
+			m.Warn(fmt.Sprintf("observations have differing dimensions (%d and %d)", d, len(c)))
+			return
+		}
+		for j, v := range c {
+			means[j] += v
+		}
+	}
+	for j := range means {
+		means[j] /= float64(len(dataset))
+	}
+	stddevs := make([]float64, d)
+	for _, o := range dataset {
+		for j, v := range o.Coordinates() {
+			stddevs[j] += (v - means[j]) * (v - means[j])
+		}
+	}
+
+	lowest, highest := math.Inf(1), 0.0
+	for j, s := range stddevs {
+		if s == 0 {
+			m.Warn(fmt.Sprintf("feature %d has zero variance", j))
+			continue
+		}
+		s = math.Sqrt(s / float64(len(dataset)))
+		lowest = math.Min(lowest, s)
+		highest = math.Max(highest, s)
+	}
+	if highest/lowest > scaleDisparity {
+		m.Warn(fmt.Sprintf("dataset not standardized and dimensions differ in scale by %.0fx", highest/lowest))
+	}
+}
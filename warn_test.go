@@ -0,0 +1,44 @@
+package kmeans
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k----n/clusters"
+)
+
+func TestWarn(t *testing.T) {
+	d := clusters.Observations{
+		clusters.Coordinates{0, 1, 0},
+		clusters.Coordinates{0, 1, 1000},
+		clusters.Coordinates{1, 1, 0},
+		clusters.Coordinates{1, 1, 1000},
+		clusters.Coordinates{1, 1, 1000},
+	}
+
+	var warnings []string
+	km := New()
+	km.Warn = func(s string) {
+		warnings = append(warnings, s)
+	}
+	if _, err := km.Partition(d, 2); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+		return
+	}
+	exp := []string{
+		"1 duplicate observations",
+		"feature 1 has zero variance",
+		"dataset not standardized and dimensions differ in scale by 1000x",
+	}
+	if !reflect.DeepEqual(warnings, exp) {
+		t.Errorf("Expected warnings %q, got %q", exp, warnings)
+	}
+
+	warnings = nil
+	if _, err := km.Partition(clusters.Observations{clusters.Coordinates{0, 1}, clusters.Coordinates{1, 0}}, 2); err != nil {
+		t.Errorf("Unexpected error partitioning: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings for a clean dataset, got %q", warnings)
+	}
+}